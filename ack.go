@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Parse a date like 2025-07-01 (local time, until the end of that day) or an RFC3339 timestamp
+func parseUntil(value string) (time.Time, error) {
+	if t, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		return t.AddDate(0, 0, 1), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %s, expecting YYYY-MM-DD or RFC3339", value)
+	}
+	return t, nil
+}
+
+// ack <container>[@host] [--until 2025-07-01]: report an outdated container as "acknowledged" and stop notifying about it
+func runAck(args []string) {
+	fs := flag.NewFlagSet("ack", flag.ExitOnError)
+	until := fs.String("until", "", "Acknowledge until this date (YYYY-MM-DD or RFC3339), forever if empty")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		log.Fatal("Usage: ack <container>[@host] [--until 2025-07-01]")
+	}
+	name := fs.Arg(0)
+	fs.Parse(fs.Args()[1:]) // allow flags after the container name
+
+	var ack Acknowledgement
+	if *until != "" {
+		t, err := parseUntil(*until)
+		if err != nil {
+			log.Fatal("Unable to parse until:", err)
+		}
+		ack.Until = &t
+	}
+
+	state.Acknowledgements[containerKey(name, "")] = ack
+	err := SaveState(statePath, state)
+	if err != nil {
+		log.Fatal("Unable to save state:", err)
+	}
+
+	if ack.Until == nil {
+		log.Println("Acknowledged", containerKey(name, ""))
+	} else {
+		log.Println("Acknowledged", containerKey(name, ""), "until", ack.Until.Format(time.RFC3339))
+	}
+}
+
+// unack <container>[@host]: remove an acknowledgement
+func runUnack(args []string) {
+	if len(args) == 0 {
+		log.Fatal("Usage: unack <container>[@host]")
+	}
+
+	delete(state.Acknowledgements, containerKey(args[0], ""))
+	err := SaveState(statePath, state)
+	if err != nil {
+		log.Fatal("Unable to save state:", err)
+	}
+	log.Println("Removed acknowledgement of", containerKey(args[0], ""))
+}
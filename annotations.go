@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"github.com/docker/docker/api/types"
+)
+
+// Standard OCI annotations, also used as image labels
+// ref: https://github.com/opencontainers/image-spec/blob/main/annotations.md
+const (
+	annotationVersion  = "org.opencontainers.image.version"
+	annotationRevision = "org.opencontainers.image.revision"
+	annotationCreated  = "org.opencontainers.image.created"
+
+	annotationDescription = "org.opencontainers.image.description"
+)
+
+// Version metadata of an image from its OCI annotations or labels
+type OCIMetadata struct {
+	Version  string `json:"version,omitempty"`
+	Revision string `json:"revision,omitempty"`
+	Created  string `json:"created,omitempty"`
+
+	Description string `json:"description,omitempty"`
+}
+
+// Read the metadata from annotations or labels, nil when there is none
+func ociMetadata(values map[string]string) *OCIMetadata {
+	m := &OCIMetadata{
+		Version:  values[annotationVersion],
+		Revision: values[annotationRevision],
+		Created:  values[annotationCreated],
+
+		Description: values[annotationDescription],
+	}
+	if *m == (OCIMetadata{}) {
+		return nil
+	}
+	return m
+}
+
+// Metadata of a local image from its labels
+func localOCIMetadata(img types.ImageInspect) *OCIMetadata {
+	if img.Config == nil {
+		return nil
+	}
+	return ociMetadata(img.Config.Labels)
+}
+
+// Metadata of a remote image from the annotations of its index, of its platform manifest, or its labels
+func remoteOCIMetadata(ctx context.Context, image string, digest string, os string, arch string) (*OCIMetadata, error) {
+	index, err := GetManifest(ctx, image, digest)
+	if err != nil {
+		return nil, err
+	}
+	if m := ociMetadata(index.Annotations); m != nil {
+		return m, nil
+	}
+
+	manifest := index
+	for _, entry := range index.Manifests {
+		if entry.Platform.OS != os || entry.Platform.Architecture != arch {
+			continue
+		}
+		if m := ociMetadata(entry.Annotations); m != nil {
+			return m, nil
+		}
+		manifest, err = GetManifest(ctx, image, entry.Digest)
+		if err != nil {
+			return nil, err
+		}
+		if m := ociMetadata(manifest.Annotations); m != nil {
+			return m, nil
+		}
+		break
+	}
+
+	if manifest.Config.Digest == "" {
+		return nil, nil
+	}
+	config, err := GetImageConfig(ctx, image, manifest.Config.Digest)
+	if err != nil {
+		return nil, err
+	}
+	return ociMetadata(config.Config.Labels), nil
+}
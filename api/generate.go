@@ -0,0 +1,4 @@
+// Package api holds the gRPC API used by fleet agents, generated from checker.proto
+package api
+
+//go:generate buf generate
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Base URL, repository key and image path of an Artifactory image.
+// With the repository path method the repository key is the first path segment (artifactory.example.com/docker-local/app),
+// with the subdomain and port methods it is configured (docker-local.artifactory.example.com/app).
+// doc: https://jfrog.com/help/r/jfrog-artifactory-documentation/the-reverse-proxy-settings
+func artifactoryRepository(image string) (base string, repoKey string, path string, err error) {
+	registry, _, _ := parseImage(image)
+	rc := registryConfig(registry)
+
+	base = strings.TrimSuffix(rc.URL, "/")
+	if base == "" {
+		base = registryURL(registry) + "/artifactory"
+	}
+
+	path = strings.TrimPrefix(image, registry+"/")
+	repoKey = rc.Repository
+	if repoKey == "" {
+		var ok bool
+		repoKey, path, ok = strings.Cut(path, "/")
+		if !ok {
+			return "", "", "", fmt.Errorf("no repository key in Artifactory image %s", image)
+		}
+	}
+	return base, repoKey, path, nil
+}
+
+// Headers authenticating with an access token, an API key or a username and password
+func artifactoryHeaders(rc RegistryConfig) http.Header {
+	headers := make(http.Header)
+	switch {
+	case rc.Token != "":
+		headers.Set("Authorization", "Bearer "+rc.Token)
+	case rc.APIKey != "":
+		headers.Set("X-JFrog-Art-Api", rc.APIKey)
+	case rc.Username != "":
+		headers.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(rc.Username+":"+rc.Password)))
+	}
+	return headers
+}
+
+// Get a path of the Docker registry API of the Artifactory repository of image
+func artifactoryGet(ctx context.Context, image string, path string, accept []string) (HTTPResponse, error) {
+	registry, _, _ := parseImage(image)
+	base, repoKey, imagePath, err := artifactoryRepository(image)
+	if err != nil {
+		return HTTPResponse{}, err
+	}
+
+	headers := artifactoryHeaders(registryConfig(registry))
+	if len(accept) > 0 {
+		headers.Set("Accept", strings.Join(accept, ", "))
+	}
+	resp, err := httpFetch(ctx, fmt.Sprintf("%s/api/docker/%s/v2/%s/%s", base, repoKey, imagePath, path), headers)
+	if err != nil {
+		return resp, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return resp, fmt.Errorf("%w: access to %s denied by Artifactory (%d), check the token or API key in the registries config", errAuthRequired, image, resp.StatusCode)
+	case resp.StatusCode == http.StatusNotFound:
+		return resp, fmt.Errorf("%w: %s in Artifactory", errNotFound, image+"/"+path)
+	case resp.StatusCode >= 400:
+		return resp, fmt.Errorf("error while getting %s of %s: %d %s", path, image, resp.StatusCode, string(resp.Body))
+	}
+	return resp, nil
+}
+
+// Resolve the digest of a tag of an Artifactory image, checked against digests when given
+func GetArtifactoryInfo(ctx context.Context, image string, tag string, digests []string) (ImageInfo, error) {
+	resp, err := artifactoryGet(ctx, image, "manifests/"+tag, manifestMediaTypes)
+	if err != nil {
+		return ImageInfo{}, err
+	}
+
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return ImageInfo{}, fmt.Errorf("no digest returned for %s:%s", image, tag)
+	}
+	// listing the digest of every tag takes one request per tag, so only the local tag is checked
+	if digests != nil && !slices.Contains(digests, image+"@"+digest) {
+		return ImageInfo{}, fmt.Errorf("%w: %s:%s no longer points to the local digest", errNotFound, image, tag)
+	}
+	return ImageInfo{Digest: digest, Tags: []string{tag}}, nil
+}
+
+// List the tags of an Artifactory image
+func GetArtifactoryTags(ctx context.Context, image string) ([]string, error) {
+	resp, err := artifactoryGet(ctx, image, "tags/list", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var list struct {
+		Tags []string `json:"tags"`
+	}
+	err = json.Unmarshal(resp.Body, &list)
+	if err != nil {
+		return nil, fmt.Errorf("server error while unmarshalling body: %s", err)
+	}
+	return list.Tags, nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/user"
+	"time"
+)
+
+// JSON lines file to append the actions taken on containers and images to
+var auditPath string
+
+// Action taken on a container or an image, for change-management evidence
+type AuditEntry struct {
+	Time      time.Time `json:"time"`
+	RunID     string    `json:"run_id"`
+	Actor     string    `json:"actor"`  // user@hostname running the check
+	Action    string    `json:"action"` // update, self-update, restart, cleanup, rebuild or stop
+	Container string    `json:"container,omitempty"`
+	Host      string    `json:"host,omitempty"`
+	Image     string    `json:"image"`
+	OldDigest string    `json:"old_digest,omitempty"`
+	NewDigest string    `json:"new_digest,omitempty"`
+	Outcome   string    `json:"outcome"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// User and host running the check, e.g. root@nas
+func auditActor() string {
+	name := "unknown"
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	hostname, _ := os.Hostname()
+	return name + "@" + hostname
+}
+
+// Append an entry to the -audit_log file and to the audit table of the -history database
+func RecordAudit(entry AuditEntry) {
+	if auditPath == "" && historyPath == "" {
+		return
+	}
+	entry.Time = timestamp()
+	entry.RunID = runID
+	entry.Actor = auditActor()
+
+	if auditPath != "" {
+		err := appendAuditLog(auditPath, entry)
+		if err != nil {
+			log.Println("Unable to write audit log:", err)
+		}
+	}
+	if historyPath != "" {
+		err := recordAuditHistory(historyPath, entry)
+		if err != nil {
+			log.Println("Unable to record audit entry:", err)
+		}
+	}
+}
+
+func appendAuditLog(path string, entry AuditEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("error while marshalling audit entry: %s", err)
+	}
+
+	// O_APPEND writes of a single line are not interleaved with other writers
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("error while opening audit log: %s", err)
+	}
+	_, err = f.Write(append(data, '\n'))
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("error while writing audit log: %s", err)
+	}
+	return f.Close()
+}
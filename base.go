@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// OCI annotations of the image an image was built from, set by docker buildx
+const (
+	annotationBaseName   = "org.opencontainers.image.base.name"
+	annotationBaseDigest = "org.opencontainers.image.base.digest"
+)
+
+// Check if the image was built locally: it was never pulled from or pushed to a registry
+func isLocallyBuilt(c Container) bool {
+	return len(c.ImageInspect.RepoDigests) == 0 && c.ImageInspect.Config != nil && c.ImageInspect.Config.Labels[annotationBaseName] != ""
+}
+
+// Check if the base image of a locally built image still is the latest one of its tag, e.g. alpine:3.19
+func checkBaseImage(ctx context.Context, c Container, result CheckResult) CheckResult {
+	labels := c.ImageInspect.Config.Labels
+	baseName, baseTag := parseReference(labels[annotationBaseName])
+	baseDigest := labels[annotationBaseDigest]
+	result.BaseImage = baseName + ":" + baseTag
+	if baseDigest == "" {
+		result.Error = fmt.Sprintf("no %s label to compare the base image with", annotationBaseDigest)
+		result.ErrorCode = "NO_BASE_DIGEST"
+		return result
+	}
+
+	latest, err := GetRemoteDockerInfo(ctx, baseName, baseTag, nil)
+	if err != nil {
+		log.Println("Unable to get remote base image:", result.Container, result.BaseImage, err)
+		result.Error = err.Error()
+		result.ErrorCode = errorCode(err)
+		result.IsLatest = lookupStatus(err)
+		return result
+	}
+	result.CurrentDigest = baseDigest
+	result.LatestDigest = latest.Digest
+
+	// the base digest is the index digest, or one platform image of it
+	if latest.Digest == baseDigest {
+		result.IsLatest = "yes"
+		return result
+	}
+	for _, image := range latest.MultiplePlatformImageInfoList {
+		if image.Digest == baseDigest {
+			result.IsLatest = "yes"
+			return result
+		}
+	}
+	result.IsLatest = "base-outdated"
+	result.LatestTags = strings.Join(latest.Tags, "|")
+	return result
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/client"
+	"github.com/docker/go-connections/nat"
+)
+
+// Container label overriding the configured update strategy
+const labelUpdateStrategy = "is-latest.update-strategy"
+
+// Find the update strategy of a container: recreate, or blue-green to verify the new image first
+func updateStrategy(c Container) string {
+	if strategy, ok := c.Labels[labelUpdateStrategy]; ok {
+		return strategy
+	}
+	if config.Update.Strategy != "" {
+		return config.Update.Strategy
+	}
+	return "recreate"
+}
+
+// Why the container can't run next to a copy of itself, empty when it can: it has to publish ports on its own
+// network namespace, have a healthcheck to verify the copy with, and no writable volumes the copy would write to as well
+func blueGreenRefusal(info types.ContainerJSON) string {
+	if len(info.HostConfig.PortBindings) == 0 || info.HostConfig.NetworkMode.IsHost() || info.HostConfig.NetworkMode.IsContainer() {
+		return "no published ports on its own network"
+	}
+	if info.Config == nil || info.Config.Healthcheck == nil || len(info.Config.Healthcheck.Test) == 0 || info.Config.Healthcheck.Test[0] == "NONE" {
+		return "no healthcheck"
+	}
+	for _, m := range info.Mounts {
+		if m.RW && (m.Type == mount.TypeVolume || m.Type == mount.TypeBind) {
+			return "writable mount " + m.Destination
+		}
+	}
+	return ""
+}
+
+// The port bindings on random host ports, the ports of the running container stay in use
+func alternatePorts(bindings nat.PortMap) nat.PortMap {
+	alternate := make(nat.PortMap, len(bindings))
+	for port, portBindings := range bindings {
+		for _, binding := range portBindings {
+			alternate[port] = append(alternate[port], nat.PortBinding{HostIP: binding.HostIP})
+		}
+	}
+	return alternate
+}
+
+// Start a green copy of the container with the latest image on alternate ports and the network alias <name>-green,
+// and only recreate the container once the copy is healthy. The running container is untouched when the copy fails.
+func UpdateBlueGreen(ctx context.Context, cli *client.Client, c Container, digest string, unpause bool) error {
+	err := pullUpdate(ctx, cli, c, digest)
+	if err != nil {
+		return err
+	}
+
+	info, err := cli.ContainerInspect(ctx, c.ID)
+	if err != nil {
+		return fmt.Errorf("error while inspecting container %s: %s", c.ID, err)
+	}
+	if reason := blueGreenRefusal(info); reason != "" {
+		log.Println("Unable to update blue-green, recreating:", c.Names[0], reason)
+		return UpdateContainer(ctx, cli, c, digest, unpause)
+	}
+
+	greenName := strings.TrimPrefix(info.Name, "/") + "-green"
+	containerConfig, networkingConfig, endpoints := recreateConfig(info, c)
+	hostConfig := *info.HostConfig
+	hostConfig.PortBindings = alternatePorts(info.HostConfig.PortBindings)
+	// the service aliases would send traffic to the copy before it is verified
+	for _, ep := range networkingConfig.EndpointsConfig {
+		ep.Aliases = []string{greenName}
+	}
+	for _, ep := range endpoints {
+		ep.Aliases = []string{greenName}
+	}
+
+	created, err := cli.ContainerCreate(ctx, containerConfig, &hostConfig, networkingConfig, nil, greenName)
+	if err != nil {
+		return fmt.Errorf("error while creating %s: %s", greenName, err)
+	}
+	defer cli.ContainerRemove(ctx, created.ID, container.RemoveOptions{Force: true})
+
+	for networkName, ep := range endpoints {
+		err = cli.NetworkConnect(ctx, networkName, created.ID, ep)
+		if err != nil {
+			return fmt.Errorf("error while connecting %s to %s: %s", greenName, networkName, err)
+		}
+	}
+	err = cli.ContainerStart(ctx, created.ID, container.StartOptions{})
+	if err != nil {
+		return fmt.Errorf("error while starting %s: %s", greenName, err)
+	}
+	// the copy is always verified, it is the point of the strategy
+	timeout := healthTimeout
+	if timeout == 0 {
+		timeout = defaultHealthTimeout
+	}
+	err = waitHealthy(ctx, cli, created.ID, timeout)
+	if err != nil {
+		return fmt.Errorf("error while starting %s: %w", greenName, err)
+	}
+
+	// swap: the image is pulled and verified, only the restart on the original ports is left
+	return UpdateContainer(ctx, cli, c, digest, unpause)
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Budgets of a run, 0 for unlimited
+var (
+	maxRequests int
+	maxDuration time.Duration
+)
+
+// What exhausted the budget of a run started at start, empty while it is within budget
+func budgetExhausted(start time.Time) string {
+	if maxRequests > 0 {
+		requests := 0
+		statsMu.Lock()
+		for _, n := range runStats.Requests {
+			requests += n
+		}
+		statsMu.Unlock()
+		if requests >= maxRequests {
+			return fmt.Sprintf("%d of -max-requests=%d registry requests made", requests, maxRequests)
+		}
+	}
+	if maxDuration > 0 && time.Since(start) >= maxDuration {
+		return fmt.Sprintf("-max-duration=%s elapsed", maxDuration)
+	}
+	return ""
+}
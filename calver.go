@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+var (
+	// Dotted calendar versions, e.g. 2024.5.1 or 2024.05
+	calverDottedRegexp = regexp.MustCompile(`^v?(?:19|20)\d{2}[.-](?:0?[1-9]|1[0-2])(?:[.-]|$)`)
+	// Compact dates, e.g. 20240501 or 20240501-2
+	calverCompactRegexp = regexp.MustCompile(`^v?((?:19|20)\d{2})(0[1-9]|1[0-2])(0[1-9]|[12]\d|3[01])(\D|$)`)
+	// Version part stripped to get the variant of a calendar version
+	calverPrefixRegexp = regexp.MustCompile(`^v?[0-9._-]*`)
+)
+
+// Check if tag is a calendar version (CalVer) or a date, as used instead of semver by apps like Home Assistant
+func isCalVer(tag string) bool {
+	return calverDottedRegexp.MatchString(tag) || calverCompactRegexp.MatchString(tag)
+}
+
+// Numeric components of a calendar version in chronological order, splitting compact dates,
+// so that 20240501 and 2024.5.1 compare equal
+func calverNumbers(tag string) []int {
+	m := calverCompactRegexp.FindStringSubmatch(tag)
+	if m == nil {
+		return nil
+	}
+	var numbers []int
+	for _, part := range m[1:4] {
+		n, _ := strconv.Atoi(part)
+		numbers = append(numbers, n)
+	}
+	rest := tag[len(m[0])-len(m[4]):]
+	for _, digits := range tagDigitsRegexp.FindAllString(rest, -1) {
+		n, err := strconv.Atoi(digits)
+		if err != nil {
+			return nil
+		}
+		numbers = append(numbers, n)
+	}
+	return numbers
+}
+
+// Scheme of a calendar version, its precision and variant: 2024.5.1 and 20240501 share one,
+// 2024.05 and 2024.5.1-alpine have others
+func calverShape(tag string) string {
+	return fmt.Sprintf("calver%d:%s", len(versionNumbers(tag)), tagShape(calverPrefixRegexp.ReplaceAllString(tag, "")))
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var githubRepoRegexp = regexp.MustCompile(`https?://github\.com/[\w.-]+/[\w.-]+`)
+
+// Turn a source repository URL into its releases page, non-GitHub URLs are returned as is
+func releasesURL(source string) string {
+	u, err := url.Parse(strings.TrimSuffix(source, ".git"))
+	if err != nil || u.Host != "github.com" {
+		return source
+	}
+
+	pathPart := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(pathPart) < 2 {
+		return source
+	}
+	return fmt.Sprintf("https://github.com/%s/%s/releases", pathPart[0], strings.TrimSuffix(pathPart[1], "."))
+}
+
+// Resolve a URL where the release notes of the image can be read
+func GetChangelogURL(ctx context.Context, container Container, image string) string {
+	// OCI annotation, ref: https://github.com/opencontainers/image-spec/blob/main/annotations.md
+	if container.ImageInspect.Config != nil {
+		if source := container.ImageInspect.Config.Labels["org.opencontainers.image.source"]; source != "" {
+			return releasesURL(source)
+		}
+	}
+
+	registry, namespace, name := parseImage(image)
+
+	switch registry {
+	case "ghcr.io":
+		// GHCR packages are usually published from the repository with the same name, nested ones under it
+		owner, pkg := ghcrPackage(image)
+		repository, _, _ := strings.Cut(pkg, "/")
+		return fmt.Sprintf("https://github.com/%s/%s/releases", owner, repository)
+	case "docker.io":
+		body, err := httpGet(ctx, fmt.Sprintf("https://registry.hub.docker.com/v2/repositories/%s/%s", namespace, name), nil)
+		if err == nil {
+			var repository struct {
+				FullDescription string `json:"full_description"`
+			}
+			if json.Unmarshal(body, &repository) == nil {
+				for _, link := range githubRepoRegexp.FindAllString(repository.FullDescription, -1) {
+					// official images link to their Dockerfile repository first, which has no releases
+					if !strings.Contains(link, "github.com/docker-library/") {
+						return releasesURL(link)
+					}
+				}
+			}
+		}
+
+		if namespace == "library" {
+			return "https://hub.docker.com/_/" + name
+		}
+		return fmt.Sprintf("https://hub.docker.com/r/%s/%s", namespace, name)
+	}
+
+	return ""
+}
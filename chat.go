@@ -0,0 +1,140 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	slackSigningSecret string
+	commandToken       string // Mattermost slash command token, or Telegram webhook secret token
+)
+
+// Answer a chat command: status [name], outdated or help
+func chatReply(text string) string {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		fields = []string{"status"}
+	}
+
+	var lines []string
+	switch fields[0] {
+	case "status", "outdated":
+		filter := strings.Join(fields[1:], " ")
+		for _, result := range latestResults() {
+			if fields[0] == "outdated" && result.IsLatest != "no" {
+				continue
+			}
+			if filter != "" && !strings.Contains(result.Container, filter) && !strings.Contains(result.Image, filter) {
+				continue
+			}
+			line := fmt.Sprintf("%s %s: %s", strings.TrimPrefix(result.Container, "/"), result.Image, result.IsLatest)
+			if result.IsLatest == "no" && result.LatestTags != "" {
+				line += " -> " + result.LatestTags
+			}
+			lines = append(lines, line)
+		}
+		if len(lines) == 0 {
+			return "No matching container"
+		}
+	default:
+		return "Usage: status [container or image], outdated [container or image]"
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Check the signature of a Slack request, refusing requests older than 5 minutes against replays
+// ref: https://api.slack.com/authentication/verifying-requests-from-slack
+func slackAuthorized(r *http.Request, body []byte) bool {
+	timestamp := r.Header.Get("X-Slack-Request-Timestamp")
+	sent, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || time.Since(time.Unix(sent, 0)).Abs() > 5*time.Minute {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(slackSigningSecret))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(r.Header.Get("X-Slack-Signature")), []byte(expected))
+}
+
+// POST /api/v1/command: Slack or Mattermost slash command, e.g. /is-latest status nginx
+// ref: https://api.slack.com/interactivity/slash-commands
+// ref: https://developers.mattermost.com/integrate/slash-commands/custom/
+func handleCommand(w http.ResponseWriter, r *http.Request) {
+	if slackSigningSecret == "" && commandToken == "" {
+		http.Error(w, "chat commands are not enabled, start with -slack_signing_secret or -command_token", http.StatusNotFound)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Slack signs its requests, Mattermost sends the token of the command
+	authorized := false
+	if r.Header.Get("X-Slack-Signature") != "" {
+		authorized = slackSigningSecret != "" && slackAuthorized(r, body)
+	} else if commandToken != "" {
+		authorized = subtle.ConstantTimeCompare([]byte(form.Get("token")), []byte(commandToken)) == 1
+	}
+	if !authorized {
+		http.Error(w, "invalid signature or token", http.StatusUnauthorized)
+		return
+	}
+
+	writeJSON(w, map[string]string{"response_type": "ephemeral", "text": chatReply(form.Get("text"))})
+}
+
+// POST /api/v1/telegram: Telegram bot webhook, answering commands like /status nginx in the webhook response
+// ref: https://core.telegram.org/bots/api#making-requests-when-getting-updates
+func handleTelegram(w http.ResponseWriter, r *http.Request) {
+	if commandToken == "" {
+		http.Error(w, "chat commands are not enabled, start with -command_token", http.StatusNotFound)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Telegram-Bot-Api-Secret-Token")), []byte(commandToken)) != 1 {
+		http.Error(w, "invalid secret token", http.StatusUnauthorized)
+		return
+	}
+
+	var update struct {
+		Message struct {
+			Text string `json:"text"`
+			Chat struct {
+				ID int64 `json:"id"`
+			} `json:"chat"`
+		} `json:"message"`
+	}
+	err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&update)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// only commands, /status@bot_name in groups
+	text, ok := strings.CutPrefix(update.Message.Text, "/")
+	if !ok {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	command, args, _ := strings.Cut(text, " ")
+	command, _, _ = strings.Cut(command, "@")
+
+	writeJSON(w, map[string]any{"method": "sendMessage", "chat_id": update.Message.Chat.ID, "text": chatReply(command + " " + args)})
+}
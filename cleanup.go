@@ -0,0 +1,80 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/client"
+)
+
+var (
+	cleanupImages bool
+	keepImages    int
+)
+
+// Check if a local image belongs to the repository, tagged or only known by its repo digest
+func isRepositoryImage(img image.Summary, repository string) bool {
+	for _, tag := range img.RepoTags {
+		if name, _ := parseReference(tag); name == repository {
+			return true
+		}
+	}
+	for _, digest := range img.RepoDigests {
+		if strings.HasPrefix(digest, repository+"@") {
+			return true
+		}
+	}
+	return false
+}
+
+// Remove the unused images of the repository of an updated container, keeping the keep most recent ones for rollback
+func CleanupImages(ctx context.Context, cli *client.Client, c Container) error {
+	if err := checkWritable("remove images"); err != nil {
+		return err
+	}
+	repository, _ := parseReference(c.Image)
+
+	containers, err := cli.ContainerList(ctx, container.ListOptions{All: true})
+	if err != nil {
+		return fmt.Errorf("error while listing containers: %s", err)
+	}
+	inUse := make(map[string]bool)
+	for _, c := range containers {
+		inUse[c.ImageID] = true
+	}
+
+	images, err := cli.ImageList(ctx, image.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error while listing images: %s", err)
+	}
+	images = slices.DeleteFunc(images, func(img image.Summary) bool {
+		return inUse[img.ID] || !isRepositoryImage(img, repository)
+	})
+	// most recent first
+	slices.SortFunc(images, func(a, b image.Summary) int {
+		return cmp.Compare(b.Created, a.Created)
+	})
+
+	for i, img := range images {
+		if i < keepImages {
+			continue
+		}
+		_, err := cli.ImageRemove(ctx, img.ID, image.RemoveOptions{PruneChildren: true})
+		entry := AuditEntry{Action: "cleanup", Host: c.Endpoint.Name, Image: repository, OldDigest: img.ID, Outcome: "removed"}
+		if err != nil {
+			log.Println("Unable to remove image:", img.ID, err)
+			entry.Outcome, entry.Error = "failed", err.Error()
+			RecordAudit(entry)
+			continue
+		}
+		RecordAudit(entry)
+		log.Printf("%10s %s %s", "[removed]", repository, img.ID)
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// Name of the command in usage and completions
+const commandName = "docker-check-is-latest"
+
+// Subcommands, check is run when none is given
+var commands = []struct {
+	name    string
+	summary string
+}{
+	{"check", "Check the containers once"},
+	{"serve", "Run as a daemon, checking at -interval, on -watch-events and serving -listen and -grpc"},
+	{"update", "Check the containers and update the outdated ones, like check -update"},
+	{"export", "Export the digests of the running images (pins) or the registry responses for them (metadata)"},
+	{"verify-pins", "Check that the pinned digests are still the ones the registries serve"},
+	{"enforce", "Report or stop the containers running digests missing from a signed allowlist"},
+	{"rewrite-compose", "Update outdated image tags and digests in compose files"},
+	{"ack", "Acknowledge an outdated container"},
+	{"unack", "Remove the acknowledgement of a container"},
+	{"login", "Store a registry token read from stdin with the credential helper"},
+	{"logout", "Remove a registry token from the credential helper"},
+	{"state", "Export the state, HTTP cache, history and audit log to an archive or import them, e.g. on a new host"},
+	{"diff", "Print what changed between two JSON reports"},
+	{"doctor", "Check Docker connectivity, registries, tokens and file permissions, printing fixes"},
+	{"completion", "Print the bash, zsh or fish completion script"},
+	{"version", "Print the version and build info, with -check whether a newer release exists"},
+}
+
+// Arguments completed after a subcommand
+var commandArgs = map[string][]string{
+	"export":     {"pins", "metadata"},
+	"state":      {"export", "import"},
+	"completion": {"bash", "zsh", "fish"},
+}
+
+// Print the commands and the global flags
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: %s [flags] [command] [flags]\n\nCommands:\n", commandName)
+	for _, c := range commands {
+		fmt.Fprintf(out, "  %-16s %s\n", c.name, c.summary)
+	}
+	fmt.Fprint(out, "\nFlags:\n")
+	flag.PrintDefaults()
+}
+
+// Environment variable of a flag, e.g. IS_LATEST_GHCR_TOKEN for -ghcr_token
+func flagEnv(name string) string {
+	return "IS_LATEST_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// Set the flags from their environment variables, before parsing the command line which takes precedence
+func flagsFromEnv() {
+	flag.VisitAll(func(f *flag.Flag) {
+		value, ok := os.LookupEnv(flagEnv(f.Name))
+		// IS_LATEST_<NAME>_FILE gives the value in a file, e.g. a Docker secret
+		if path, isFile := os.LookupEnv(flagEnv(f.Name) + "_FILE"); !ok && isFile {
+			var err error
+			value, err = readSecretFile(path)
+			if err != nil {
+				log.Fatal("Invalid value of ", flagEnv(f.Name), "_FILE: ", err)
+			}
+			ok = true
+		}
+		if !ok {
+			return
+		}
+		// repeatable flags take comma-separated values
+		values := []string{value}
+		if _, ok := f.Value.(*stringList); ok {
+			values = strings.Split(value, ",")
+		}
+		for _, v := range values {
+			err := f.Value.Set(v)
+			if err != nil {
+				log.Fatal("Invalid value of ", flagEnv(f.Name), ": ", err)
+			}
+		}
+	})
+}
+
+// export pins|metadata [flags]
+func runExport(args []string) {
+	if len(args) == 0 {
+		log.Fatal("Usage: export pins|metadata [flags]")
+	}
+	switch args[0] {
+	case "pins":
+		runExportPins(args[1:])
+	case "metadata":
+		runExportMetadata(args[1:])
+	default:
+		log.Fatal("Unknown export: ", args[0])
+	}
+}
+
+// completion bash|zsh|fish: print the completion script of the shell
+func runCompletion(args []string) {
+	if len(args) != 1 {
+		log.Fatal("Usage: completion bash|zsh|fish")
+	}
+	var script string
+	switch args[0] {
+	case "bash":
+		script = bashCompletion()
+	case "zsh":
+		script = zshCompletion()
+	case "fish":
+		script = fishCompletion()
+	default:
+		log.Fatal("Unknown shell: ", args[0])
+	}
+	fmt.Print(script)
+}
+
+// Names of the global flags with a dash, e.g. -output
+func flagNames() []string {
+	var names []string
+	flag.VisitAll(func(f *flag.Flag) {
+		names = append(names, "-"+f.Name)
+	})
+	return names
+}
+
+func commandNames() []string {
+	var names []string
+	for _, c := range commands {
+		names = append(names, c.name)
+	}
+	return names
+}
+
+func bashCompletion() string {
+	var b strings.Builder
+	b.WriteString("_docker_check_is_latest() {\n")
+	b.WriteString("\tlocal cur=\"${COMP_WORDS[COMP_CWORD]}\" prev=\"${COMP_WORDS[COMP_CWORD-1]}\"\n")
+	b.WriteString("\tcase \"$prev\" in\n")
+	for _, name := range sortedKeys(commandArgs) {
+		fmt.Fprintf(&b, "\t%s) COMPREPLY=($(compgen -W \"%s\" -- \"$cur\")); return ;;\n", name, strings.Join(commandArgs[name], " "))
+	}
+	b.WriteString("\tesac\n")
+	b.WriteString("\tif [[ \"$cur\" == -* ]]; then\n")
+	fmt.Fprintf(&b, "\t\tCOMPREPLY=($(compgen -W \"%s\" -- \"$cur\"))\n", strings.Join(flagNames(), " "))
+	b.WriteString("\telse\n")
+	fmt.Fprintf(&b, "\t\tCOMPREPLY=($(compgen -W \"%s\" -- \"$cur\"))\n", strings.Join(commandNames(), " "))
+	b.WriteString("\tfi\n")
+	b.WriteString("}\n")
+	fmt.Fprintf(&b, "complete -o default -F _docker_check_is_latest %s\n", commandName)
+	return b.String()
+}
+
+// Quote a value for zsh and fish single-quoted strings
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func zshCompletion() string {
+	// colons separate the name from the description in _describe
+	describe := func(name, summary string) string {
+		return shellQuote(name + ":" + strings.ReplaceAll(summary, ":", `\:`))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "#compdef %s\n\n", commandName)
+	b.WriteString("_docker_check_is_latest() {\n")
+	b.WriteString("\tlocal -a commands flags\n")
+	b.WriteString("\tcommands=(\n")
+	for _, c := range commands {
+		fmt.Fprintf(&b, "\t\t%s\n", describe(c.name, c.summary))
+	}
+	b.WriteString("\t)\n")
+	b.WriteString("\tflags=(\n")
+	flag.VisitAll(func(f *flag.Flag) {
+		fmt.Fprintf(&b, "\t\t%s\n", describe("-"+f.Name, f.Usage))
+	})
+	b.WriteString("\t)\n")
+	b.WriteString("\tcase $words[CURRENT-1] in\n")
+	for _, name := range sortedKeys(commandArgs) {
+		fmt.Fprintf(&b, "\t%s) compadd %s; return ;;\n", name, strings.Join(commandArgs[name], " "))
+	}
+	b.WriteString("\tesac\n")
+	b.WriteString("\tif [[ $PREFIX == -* ]]; then\n")
+	b.WriteString("\t\t_describe 'flag' flags\n")
+	b.WriteString("\telse\n")
+	b.WriteString("\t\t_describe 'command' commands\n")
+	b.WriteString("\t\t_files\n")
+	b.WriteString("\tfi\n")
+	b.WriteString("}\n\n")
+	fmt.Fprintf(&b, "compdef _docker_check_is_latest %s\n", commandName)
+	return b.String()
+}
+
+func fishCompletion() string {
+	var b strings.Builder
+	for _, c := range commands {
+		fmt.Fprintf(&b, "complete -c %s -n __fish_use_subcommand -a %s -d %s\n", commandName, c.name, shellQuote(c.summary))
+	}
+	for _, name := range sortedKeys(commandArgs) {
+		fmt.Fprintf(&b, "complete -c %s -n '__fish_seen_subcommand_from %s' -f -a %s\n", commandName, name, shellQuote(strings.Join(commandArgs[name], " ")))
+	}
+	flag.VisitAll(func(f *flag.Flag) {
+		// -o is a single-dash option, as parsed by the flag package
+		fmt.Fprintf(&b, "complete -c %s -o %s -d %s\n", commandName, f.Name, shellQuote(f.Usage))
+	})
+	return b.String()
+}
+
+// Print to stderr and exit when the command is unknown
+func unknownCommand(name string) {
+	fmt.Fprintf(os.Stderr, "Unknown command: %s\n\n", name)
+	usage()
+	os.Exit(2)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Report the exited one-shot containers of an image as one result
+var collapseDuplicates bool
+
+// Container label marking a container as one-shot, e.g. created by ofelia or another cron-like scheduler
+const labelOneShot = "is-latest.one-shot"
+
+// Check if c was created by a scheduler to run once: a compose run, a swarm job task (or an old task
+// kept by the task history of a service), or a container with the is-latest.one-shot label
+func isOneShot(c Container) bool {
+	if value, ok := c.Labels[labelOneShot]; ok {
+		oneShot, _ := strconv.ParseBool(value)
+		return oneShot
+	}
+	if strings.EqualFold(c.Labels["com.docker.compose.oneoff"], "true") {
+		return true
+	}
+	_, task := c.Labels["com.docker.swarm.task.id"]
+	return task && c.State != "running"
+}
+
+// Replace the results of the exited one-shot containers of the same image and status on a host by the first of them,
+// counting the containers it stands for in Collapsed
+func collapseResults(results []CheckResult) []CheckResult {
+	if !collapseDuplicates {
+		return results
+	}
+	collapsed := make([]CheckResult, 0, len(results))
+	index := make(map[string]int)
+	for _, result := range results {
+		if !result.OneShot || result.State == "running" {
+			collapsed = append(collapsed, result)
+			continue
+		}
+		key := strings.Join([]string{result.Host, result.Image, result.IsLatest}, "\x00")
+		if i, ok := index[key]; ok {
+			collapsed[i].Collapsed++
+			continue
+		}
+		index[key] = len(collapsed)
+		result.Collapsed = 1
+		collapsed = append(collapsed, result)
+	}
+	return collapsed
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"slices"
+	"strings"
+)
+
+// Container label overriding the tag its image is compared against
+const labelCompareTag = "is-latest.compare-tag"
+
+// Container label overriding the configured image of record
+const labelCheckAgainst = "is-latest.check-against"
+
+// Image the image of a container is compared against instead of the one it was pulled from, empty for none
+func checkAgainst(c Container, imageName string) string {
+	if image, ok := c.Labels[labelCheckAgainst]; ok && image != "" {
+		return image
+	}
+	if image, ok := config.CheckAgainst[imageName]; ok {
+		return image
+	}
+	return registryMirrorOf(imageName)
+}
+
+// Tag the image of a container is compared against: the label, the config entry of the image, or "latest"
+func compareTag(c Container, imageName string) string {
+	if tag, ok := c.Labels[labelCompareTag]; ok && tag != "" {
+		return tag
+	}
+	if tag, ok := config.CompareTags[imageName]; ok && tag != "" {
+		return tag
+	}
+	return "latest"
+}
+
+// Reference the local image of c is checked as: the reference c was started with while the image still carries it,
+// otherwise a tag of the same repository it carries, e.g. app:1.2 when app:latest was pulled again since
+func localReference(c Container) string {
+	// a container started by image ID is checked as a tag of the image, none when it has no tag
+	if isImageID(c) {
+		for _, tag := range c.ImageInspect.RepoTags {
+			if name, t := parseReference(tag); t == compareTag(c, name) {
+				return tag
+			}
+		}
+		if len(c.ImageInspect.RepoTags) > 0 {
+			return c.ImageInspect.RepoTags[0]
+		}
+		return ""
+	}
+
+	imageName, imageTag := parseReference(c.Image)
+	reference := imageName + ":" + imageTag
+	tags := c.ImageInspect.RepoTags
+	if len(tags) == 0 || slices.Contains(tags, reference) {
+		return c.Image
+	}
+
+	var candidates []string
+	for _, tag := range tags {
+		if name, t := parseReference(tag); name == imageName {
+			// the tag compared against is the best match, the local image is then up to date with it
+			if t == compareTag(c, imageName) {
+				return tag
+			}
+			candidates = append(candidates, tag)
+		}
+	}
+	if len(candidates) == 0 {
+		return c.Image
+	}
+	return candidates[0]
+}
+
+// Check if c was started by the ID of its image rather than a reference, e.g. docker run 4f2a9c1e
+func isImageID(c Container) bool {
+	id := strings.TrimPrefix(c.Image, "sha256:")
+	return id != "" && strings.HasPrefix(strings.TrimPrefix(c.ImageID, "sha256:"), id)
+}
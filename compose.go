@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	composeFileRegexp  = regexp.MustCompile(`^(docker-)?compose.*\.ya?ml$`)
+	yamlFileRegexp     = regexp.MustCompile(`\.ya?ml$`)
+	composeImageRegexp = regexp.MustCompile(`^(\s*(?:-\s*)?image:\s*["']?)([^"'\s#]+)(.*)$`)
+	tagDigitsRegexp    = regexp.MustCompile(`\d+`)
+)
+
+// Reduce a tag to its scheme, e.g. 1.25.3-alpine -> 0.0.0-alpine, calendar versions share one per variant
+func tagShape(tag string) string {
+	if isCalVer(tag) {
+		return calverShape(tag)
+	}
+	return tagDigitsRegexp.ReplaceAllString(tag, "0")
+}
+
+// Tags carried by a remote digest
+func remoteTagsWithDigest(ctx context.Context, image string, digest string) ([]string, error) {
+	registry, _, _ := parseImage(image)
+	switch registry {
+	case "docker.io":
+		tags, err := GetDockerHubTags(ctx, image)
+		if err != nil {
+			return nil, err
+		}
+		return tagsWithDigest(tags, digest), nil
+	default:
+		info, err := GetRemoteDockerInfo(ctx, image, "", []string{image + "@" + digest})
+		if err != nil {
+			return nil, err
+		}
+		return info.Tags, nil
+	}
+}
+
+// Find the up-to-date form of a compose image reference, which is unchanged when it is already up-to-date
+func latestReference(ctx context.Context, reference string) (string, error) {
+	name, _, isPinned := strings.Cut(reference, "@")
+	imageName, imageTag := parseReference(name)
+
+	if isPinned {
+		digest, err := GetRemoteDigest(ctx, imageName, imageTag)
+		if err != nil {
+			return reference, err
+		}
+		return name + "@" + digest, nil
+	}
+
+	// compose files have no container labels, only the config can override the compared tag
+	targetTag, err := resolveTargetTag(ctx, Container{}, imageName, imageTag)
+	if err != nil || imageTag == targetTag {
+		return reference, err
+	}
+	// the newest tag matching the pattern is the up-to-date form
+	if tagPattern(Container{}, imageName) != "" {
+		return strings.TrimSuffix(name, ":"+imageTag) + ":" + targetTag, nil
+	}
+	latestDigest, err := GetRemoteDigest(ctx, imageName, targetTag)
+	if err != nil {
+		return reference, err
+	}
+	digest, err := GetRemoteDigest(ctx, imageName, imageTag)
+	if err != nil || digest == latestDigest {
+		return reference, err
+	}
+
+	tags, err := remoteTagsWithDigest(ctx, imageName, latestDigest)
+	if err != nil {
+		return reference, err
+	}
+	// keep the tag scheme, e.g. 1.25 -> 1.27 but not 1.25 -> 1.27.0
+	for _, tag := range tags {
+		if tag != imageTag && tagShape(tag) == tagShape(imageTag) {
+			return strings.TrimSuffix(name, ":"+imageTag) + ":" + tag, nil
+		}
+	}
+	return reference, nil
+}
+
+// Print a unified diff of lines replaced in place
+func unifiedDiff(path string, before []string, after []string) string {
+	const context = 3
+
+	var changed []int
+	for i := range before {
+		if before[i] != after[i] {
+			changed = append(changed, i)
+		}
+	}
+	if len(changed) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- a/%s\n+++ b/%s\n", path, path)
+	for k := 0; k < len(changed); {
+		start := max(changed[k]-context, 0)
+		end := changed[k] + context + 1
+		// merge changes whose context overlaps into one hunk
+		for k+1 < len(changed) && changed[k+1]-context <= end {
+			k++
+			end = changed[k] + context + 1
+		}
+		end = min(end, len(before))
+		k++
+
+		fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@\n", start+1, end-start, start+1, end-start)
+		for i := start; i < end; i++ {
+			if before[i] == after[i] {
+				b.WriteString(" " + before[i] + "\n")
+			} else {
+				b.WriteString("-" + before[i] + "\n+" + after[i] + "\n")
+			}
+		}
+	}
+	return b.String()
+}
+
+// rewrite-compose [-write] [-sarif path] [-manifests] <dir>: update outdated image tags and digests in compose files
+// and print a unified diff
+func runRewriteCompose(args []string) {
+	flags := flag.NewFlagSet("rewrite-compose", flag.ExitOnError)
+	write := flags.Bool("write", false, "Write the changes to the compose files")
+	sarifPath := flags.String("sarif", "", "Write the outdated references with their file and line as SARIF, for code scanning")
+	manifests := flags.Bool("manifests", false, "Also scan the other YAML files, e.g. Kubernetes manifests")
+	flags.Parse(args)
+	root := "."
+	if flags.NArg() > 0 {
+		root = flags.Arg(0)
+	}
+
+	resetCache()
+	var findings []fileFinding
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !composeFileRegexp.MatchString(d.Name()) && !(*manifests && yamlFileRegexp.MatchString(d.Name())) {
+			return nil
+		}
+		rel, _ := filepath.Rel(root, path)
+		rel = filepath.ToSlash(rel)
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		before := strings.Split(string(data), "\n")
+		after := make([]string, len(before))
+		for i, line := range before {
+			after[i] = line
+			m := composeImageRegexp.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+
+			reference, err := latestReference(context.Background(), m[2])
+			if err != nil {
+				log.Println("Unable to resolve latest reference:", path, m[2], err)
+				continue
+			}
+			after[i] = m[1] + reference + m[3]
+			if reference != m[2] {
+				findings = append(findings, fileFinding{Path: rel, Line: i + 1, Column: len(m[1]) + 1, Reference: m[2], Latest: reference})
+			}
+		}
+
+		diff := unifiedDiff(rel, before, after)
+		if diff == "" {
+			return nil
+		}
+		fmt.Print(diff)
+
+		if *write {
+			return os.WriteFile(path, []byte(strings.Join(after, "\n")), d.Type().Perm())
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatal("Unable to rewrite compose files:", err)
+	}
+
+	if *sarifPath != "" {
+		data, err := renderSARIF(findings)
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = writeFileAtomic(*sarifPath, data)
+		if err != nil {
+			log.Fatal("Unable to write file:", err)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+type Config struct {
+	Notify NotifyConfig `json:"notify"`
+	Update UpdateConfig `json:"update"`
+
+	// Tag to compare the images against instead of latest, by image name (e.g. "nginx": "1-alpine")
+	CompareTags map[string]string `json:"compare_tags"`
+
+	// Image of record to compare images pulled from a mirror or cache against, by image name
+	// (e.g. "cache.example.com/org/app": "ghcr.io/org/app")
+	CheckAgainst map[string]string `json:"check_against"`
+
+	// Pull-through cache of an upstream registry, by upstream registry (e.g. "docker.io": "cache.internal:5000"),
+	// whose copy of the latest images is checked against upstream
+	PullThroughCaches map[string]string `json:"pull_through_caches"`
+
+	// Acceptable tags, compared against the newest matching one instead of latest, by image name
+	// (e.g. "nginx": "^1\\.25\\.\\d+-alpine$", a glob when it doesn't start with ^)
+	TagPatterns map[string]string `json:"tag_patterns"`
+
+	// Version command and latest version source of images whose tags don't tell the running version, by image name
+	VersionCheck map[string]VersionSource `json:"version_check"`
+
+	// GitHub repositories whose latest release the version label of the image is compared with, by image name
+	ReleaseSources map[string]string `json:"release_sources"`
+
+	// Rebuild hook of locally built images whose base image is outdated, a webhook URL or a shell command, by image name
+	Rebuild map[string]string `json:"rebuild"`
+
+	// New repository of the repositories that moved, by image name (e.g. "bitnami/nginx": "bitnamilegacy/nginx")
+	RepositoryAliases map[string]string `json:"repository_aliases"`
+
+	// Images skipped without a lookup, glob patterns matched against the full reference (e.g. "*/postgres:*")
+	Ignore []string `json:"ignore"`
+
+	// Settings of the registries, by host or host pattern, and the registries without built-in support by their type
+	Registries map[string]RegistryConfig `json:"registries"`
+}
+
+type RegistryConfig struct {
+	Type     string `json:"type"` // harbor, artifactory, or registry for any other Docker registry API
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Scheme   string `json:"scheme"` // http for plain HTTP registries, https by default
+
+	// Extra headers of the requests to the host and its subdomains, e.g. required by a corporate proxy
+	Headers map[string]string `json:"headers"`
+
+	RateLimit          float64 `json:"rate_limit"`           // requests per second, unlimited when 0
+	CAFile             string  `json:"ca_file"`              // PEM certificates trusted besides the system ones
+	InsecureSkipVerify bool    `json:"insecure_skip_verify"` // don't verify the TLS certificate, for lab registries
+	MirrorOf           string  `json:"mirror_of"`            // registry of record of a mirror, e.g. docker.io, its images are compared against
+
+	// Registry APIs looked up in turn, each after the previous one failed, e.g. ["m.daocloud.io/docker.io", "docker.io"];
+	// the registry itself is tried last when not listed
+	Mirrors []string `json:"mirrors"`
+
+	// Artifactory
+	Token      string `json:"token"`      // access token
+	APIKey     string `json:"api_key"`    // instead of a token
+	URL        string `json:"url"`        // base URL, https://<host>/artifactory by default
+	Repository string `json:"repository"` // repository key for the subdomain and port methods
+}
+
+var (
+	configPath string
+	config     Config
+)
+
+// How often the daemon looks for changes of the config file
+const configPollInterval = 10 * time.Second
+
+// Read the JSON config file
+func LoadConfig(path string) (Config, error) {
+	var c Config
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return c, fmt.Errorf("error while reading config: %s", err)
+	}
+
+	err = json.Unmarshal(data, &c)
+	if err != nil {
+		return c, fmt.Errorf("error while unmarshalling config: %s", err)
+	}
+	err = validateConfig(&c)
+	if err != nil {
+		return c, fmt.Errorf("error while validating config: %s", err)
+	}
+	return c, nil
+}
+
+// Check the values a run would only fail on later, e.g. in the middle of updating, and parse the durations
+func validateConfig(c *Config) error {
+	if c.Notify.MinSeverity != "" && !slices.Contains(severities, c.Notify.MinSeverity) {
+		return fmt.Errorf("unknown notify.min_severity %q, expecting %s", c.Notify.MinSeverity, strings.Join(severities, ", "))
+	}
+	if c.Notify.DedupeWindow != "" {
+		window, err := time.ParseDuration(c.Notify.DedupeWindow)
+		if err != nil || window < 0 {
+			return fmt.Errorf("notify.dedupe_window %q must be a positive duration, e.g. 24h", c.Notify.DedupeWindow)
+		}
+		c.Notify.dedupeWindow = window
+	}
+	// a misspelled route would silently notify nobody
+	var names []string
+	for _, n := range configuredNotifiers(c.Notify) {
+		names = append(names, n.Name())
+	}
+	for _, severity := range sortedKeys(c.Notify.Routes) {
+		if !slices.Contains(severities, severity) && severity != "unknown" && severity != "*" {
+			return fmt.Errorf("unknown notify.routes severity %q, expecting %s, unknown or *", severity, strings.Join(severities, ", "))
+		}
+		for _, name := range c.Notify.Routes[severity] {
+			if !slices.Contains(names, name) {
+				return fmt.Errorf("unknown notifier %q in notify.routes of %s, expecting one of %s", name, severity, strings.Join(names, ", "))
+			}
+		}
+	}
+	if c.Update.MaintenanceWindow != "" {
+		if _, err := parseMaintenanceWindows(c.Update.MaintenanceWindow); err != nil {
+			return err
+		}
+	}
+	for _, image := range sortedKeys(c.Update.Policy) {
+		if _, ok := updatePolicies[c.Update.Policy[image]]; !ok {
+			return fmt.Errorf("unknown update policy %q of %s", c.Update.Policy[image], image)
+		}
+	}
+	if _, ok := updatePolicies[c.Update.DefaultPolicy]; c.Update.DefaultPolicy != "" && !ok {
+		return fmt.Errorf("unknown default update policy %q", c.Update.DefaultPolicy)
+	}
+	for _, state := range sortedKeys(c.Update.States) {
+		if err := validateStateAction(state, c.Update.States[state]); err != nil {
+			return fmt.Errorf("error in update.states: %s", err)
+		}
+	}
+	if c.Update.Strategy != "" && c.Update.Strategy != "recreate" && c.Update.Strategy != "blue-green" {
+		return fmt.Errorf("unknown update strategy %q", c.Update.Strategy)
+	}
+	return nil
+}
+
+// Apply the config file and the token files again, without losing the state and results kept in memory
+func reloadConfig() error {
+	// nothing is applied unless everything loads
+	c, err := LoadConfig(configPath)
+	if err != nil {
+		return err
+	}
+	secrets, err := readSecretFiles()
+	if err != nil {
+		return err
+	}
+
+	// not in the middle of a check, a spread run lets it through between its lookups
+	runMu.Lock()
+	defer runMu.Unlock()
+	config = c
+	applySecrets(secrets)
+	credentialsMu.Lock()
+	clear(storedCredentials)
+	credentialsMu.Unlock()
+	loadStoredCredentials()
+	return nil
+}
+
+// Reload the config on SIGHUP and when the config file changes
+func watchConfig() {
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+
+	modified := func() time.Time {
+		info, err := os.Stat(configPath)
+		if err != nil {
+			return time.Time{}
+		}
+		return info.ModTime()
+	}
+	last := modified()
+
+	ticker := time.NewTicker(configPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-reload:
+		case <-ticker.C:
+			if m := modified(); m.Equal(last) || m.IsZero() {
+				continue
+			}
+		}
+		last = modified()
+
+		err := reloadConfig()
+		if err != nil {
+			log.Println("Unable to reload config, keeping the previous one:", err)
+			continue
+		}
+		log.Println("Reloaded config:", configPath)
+		forgetAllLookups()
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+)
+
+// Driver type of the containerd image store of Docker 24+, in the DriverStatus of docker info
+const containerdSnapshotter = "io.containerd.snapshotter.v1"
+
+// Check if the daemon keeps its images in the containerd image store rather than the classic graph drivers
+func usesContainerdStore(ctx context.Context, cli *client.Client) bool {
+	info, err := cli.Info(ctx)
+	if err != nil {
+		return false
+	}
+	return slices.ContainsFunc(info.DriverStatus, func(status [2]string) bool {
+		return status[0] == "driver-type" && status[1] == containerdSnapshotter
+	})
+}
+
+// Bring the image inspect of the containerd image store to the form of the classic one, which the lookups expect
+func normalizeContainerdImage(img *types.ImageInspect) {
+	// names may be fully qualified, e.g. docker.io/library/nginx@sha256:...
+	for i, repoDigest := range img.RepoDigests {
+		name, digest, _ := strings.Cut(repoDigest, "@")
+		img.RepoDigests[i] = familiarName(name) + "@" + digest
+	}
+	for i, tag := range img.RepoTags {
+		img.RepoTags[i] = familiarName(tag)
+	}
+
+}
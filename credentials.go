@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Credential helper storing the registry tokens, e.g. osxkeychain, secretservice, wincred or pass
+var credentialHelper string
+
+// Credentials exchanged with a docker-credential-helper
+// ref: https://github.com/docker/docker-credential-helpers#development
+type helperCredentials struct {
+	ServerURL string `json:"ServerURL"`
+	Username  string `json:"Username"`
+	Secret    string `json:"Secret"`
+}
+
+// Credentials read from the helper by registry, nil for registries it has none for
+var (
+	storedCredentials = make(map[string]*helperCredentials)
+	credentialsMu     sync.Mutex // the checks of containers read them concurrently
+)
+
+// The -credential_helper, or the credsStore of the docker CLI config
+func credentialHelperName() string {
+	if credentialHelper != "" {
+		return credentialHelper
+	}
+	data, err := os.ReadFile(filepath.Join(dockerConfigDir(), "config.json"))
+	if err != nil {
+		return ""
+	}
+	var dockerConfig struct {
+		CredsStore string `json:"credsStore"`
+	}
+	if json.Unmarshal(data, &dockerConfig) != nil {
+		return ""
+	}
+	return dockerConfig.CredsStore
+}
+
+// Run an action of the credential helper with input on stdin
+func runCredentialHelper(action string, input []byte) ([]byte, error) {
+	helper := credentialHelperName()
+	if helper == "" {
+		return nil, fmt.Errorf("no credential helper, set -credential_helper or credsStore in the docker config")
+	}
+	cmd := exec.Command("docker-credential-"+helper, action)
+	cmd.Stdin = bytes.NewReader(input)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("error while running docker-credential-%s %s: %s %s", helper, action, err, strings.TrimSpace(string(out)))
+	}
+	return out, nil
+}
+
+// Server URL of registry in the credential helper, the docker CLI stores Docker Hub under its legacy index URL
+func helperServerURL(registry string) string {
+	if registry == "docker.io" {
+		return "https://index.docker.io/v1/"
+	}
+	return registry
+}
+
+// Credentials of registry stored by the credential helper, nil when it has none
+func getStoredCredentials(registry string) *helperCredentials {
+	credentialsMu.Lock()
+	defer credentialsMu.Unlock()
+	if c, ok := storedCredentials[registry]; ok {
+		return c
+	}
+	storedCredentials[registry] = nil
+	if credentialHelperName() == "" {
+		return nil
+	}
+
+	out, err := runCredentialHelper("get", []byte(helperServerURL(registry)))
+	if err != nil {
+		return nil
+	}
+	var c helperCredentials
+	err = json.Unmarshal(out, &c)
+	if err != nil || c.Secret == "" {
+		return nil
+	}
+	storedCredentials[registry] = &c
+	return &c
+}
+
+// Fill the tokens missing from the flags and the config with the stored credentials
+func loadStoredCredentials() {
+	applyRegistryCredentials()
+	if ghcr_token == "" {
+		if c := getStoredCredentials("ghcr.io"); c != nil {
+			ghcr_token = c.Secret
+		}
+	}
+	if dockerHubUsername == "" && dockerHubToken == "" {
+		if c := getStoredCredentials("docker.io"); c != nil {
+			dockerHubUsername, dockerHubToken = c.Username, c.Secret
+		}
+	}
+	for registry, rc := range config.Registries {
+		if rc.Username != "" || rc.Token != "" || rc.APIKey != "" || isRegistryPattern(registry) {
+			continue
+		}
+		if c := getStoredCredentials(registry); c != nil {
+			rc.Username, rc.Password = c.Username, c.Secret
+			config.Registries[registry] = rc
+		}
+	}
+}
+
+// login [-username token] <registry>: store the token read from stdin with the credential helper
+func runLogin(args []string) {
+	fs := flag.NewFlagSet("login", flag.ExitOnError)
+	username := fs.String("username", "token", "Username of the token, e.g. the robot account of Harbor")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		log.Fatal("Usage: login [-username token] <registry> < token")
+	}
+	registry := fs.Arg(0)
+
+	// read from stdin, a token given as argument would show up in the process list
+	secret, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	secret = strings.TrimRight(secret, "\r\n")
+	if secret == "" {
+		log.Fatal("Unable to read token from stdin:", err)
+	}
+
+	input, err := json.Marshal(helperCredentials{ServerURL: helperServerURL(registry), Username: *username, Secret: secret})
+	if err != nil {
+		log.Fatal("Unable to marshal credentials:", err)
+	}
+	_, err = runCredentialHelper("store", input)
+	if err != nil {
+		log.Fatal("Unable to store credentials:", err)
+	}
+	fmt.Println("Stored credentials of", registry)
+}
+
+// logout <registry>: erase the credentials stored with the credential helper
+func runLogout(args []string) {
+	if len(args) != 1 {
+		log.Fatal("Usage: logout <registry>")
+	}
+	_, err := runCredentialHelper("erase", []byte(helperServerURL(args[0])))
+	if err != nil {
+		log.Fatal("Unable to erase credentials:", err)
+	}
+	fmt.Println("Removed credentials of", args[0])
+}
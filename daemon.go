@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+)
+
+var (
+	interval    time.Duration
+	watchEvents bool
+)
+
+// Subscribe to container creations and image pulls of every endpoint
+func subscribeEvents(ctx context.Context) (<-chan events.Message, <-chan error) {
+	messages := make(chan events.Message)
+	errs := make(chan error, 1)
+
+	endpoints, err := DockerEndpoints()
+	if err != nil {
+		errs <- err
+		return messages, errs
+	}
+
+	for _, endpoint := range endpoints {
+		cli, err := NewDockerClient(endpoint)
+		if err != nil {
+			errs <- err
+			return messages, errs
+		}
+
+		endpointMessages, endpointErrs := cli.Events(ctx, events.ListOptions{
+			Filters: filters.NewArgs(
+				filters.Arg("type", string(events.ContainerEventType)),
+				filters.Arg("type", string(events.ImageEventType)),
+				filters.Arg("event", string(events.ActionCreate)),
+				filters.Arg("event", string(events.ActionPull)),
+			),
+		})
+		go func() {
+			for {
+				select {
+				case m := <-endpointMessages:
+					select {
+					case messages <- m:
+					case <-ctx.Done():
+						return
+					}
+				case err := <-endpointErrs:
+					select {
+					case errs <- err:
+					default:
+					}
+					return
+				}
+			}
+		}()
+	}
+	return messages, errs
+}
+
+// Check all containers at every interval, and single containers on docker events
+func runDaemon() {
+	ctx := context.Background()
+
+	if listenAddr != "" {
+		go func() {
+			log.Fatal("Unable to serve:", Serve(listenAddr))
+		}()
+	}
+
+	if grpcAddr != "" {
+		go func() {
+			log.Fatal("Unable to serve gRPC:", ServeGRPC(grpcAddr))
+		}()
+	}
+
+	if configPath != "" {
+		go watchConfig()
+	}
+
+	if watchdog := sdWatchdogInterval(); watchdog > 0 {
+		go func() {
+			for range time.Tick(watchdog) {
+				sdNotify("WATCHDOG=1")
+			}
+		}()
+	}
+
+	err := run(filters.NewArgs(), 0)
+	if err != nil {
+		log.Println("Unable to check containers:", err)
+	}
+
+	err = sdNotify("READY=1")
+	if err != nil {
+		log.Println("Unable to notify systemd:", err)
+	}
+
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	var messages <-chan events.Message
+	var errs <-chan error
+	cancel := func() {}
+	if watchEvents {
+		var watchCtx context.Context
+		watchCtx, cancel = context.WithCancel(ctx)
+		messages, errs = subscribeEvents(watchCtx)
+	}
+
+	for {
+		var filter filters.Args
+		var spread time.Duration
+		select {
+		case <-tick:
+			filter = filters.NewArgs()
+			// leave some of the interval to finish the run before the next tick
+			if spreadChecks {
+				spread = interval * 9 / 10
+			}
+		case m := <-messages:
+			switch m.Type {
+			case events.ContainerEventType:
+				filter = filters.NewArgs(filters.Arg("id", m.Actor.ID))
+			case events.ImageEventType:
+				// the actor of a pull is the pulled reference, e.g. nginx:latest
+				filter = filters.NewArgs(filters.Arg("ancestor", m.Actor.ID))
+			default:
+				continue
+			}
+		case err := <-errs:
+			// denied by a socket proxy, retrying won't help
+			if isForbidden(err) {
+				log.Println("Docker events are forbidden, only checking at -interval:", err)
+				cancel()
+				messages, errs = nil, nil
+				continue
+			}
+			log.Println("Unable to watch docker events, retrying:", err)
+			// resubscribe every endpoint, not only the failed one
+			cancel()
+			time.Sleep(10 * time.Second)
+			var watchCtx context.Context
+			watchCtx, cancel = context.WithCancel(ctx)
+			messages, errs = subscribeEvents(watchCtx)
+			continue
+		}
+
+		err := run(filter, spread)
+		if err != nil {
+			log.Println("Unable to check containers:", err)
+		}
+	}
+}
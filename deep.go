@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+)
+
+// Resolve outdated images down to their config digest ("config") or layers ("layers"), off when empty
+var deepCompare string
+
+// Resolve the manifest of the os/arch image of image at reference, a tag or digest of an index or manifest
+func platformManifest(ctx context.Context, image string, reference string, os string, arch string) (Manifest, error) {
+	manifest, err := GetManifest(ctx, image, reference)
+	if err != nil || len(manifest.Manifests) == 0 {
+		return manifest, err
+	}
+	for _, entry := range manifest.Manifests {
+		if entry.Platform.OS == os && entry.Platform.Architecture == arch {
+			return GetManifest(ctx, image, entry.Digest)
+		}
+	}
+	return Manifest{}, fmt.Errorf("%w: no %s/%s image in %s@%s", errPlatformMismatch, os, arch, image, reference)
+}
+
+// Check if the remote image at digest is the local image of c despite a different index or manifest digest,
+// e.g. when a mirror rewrote the manifests
+func sameImage(ctx context.Context, c Container, image string, digest string) (bool, error) {
+	// the ID of an image is the digest of its index or manifest with the containerd image store
+	if digest == c.ImageInspect.ID {
+		return true, nil
+	}
+	manifest, err := platformManifest(ctx, image, digest, c.ImageInspect.Os, c.ImageInspect.Architecture)
+	if err != nil {
+		return false, err
+	}
+	// and the digest of its config with the classic one
+	if manifest.Config.Digest == c.ImageInspect.ID {
+		return true, nil
+	}
+	if deepCompare != "layers" {
+		return false, nil
+	}
+
+	config, err := GetImageConfig(ctx, image, manifest.Config.Digest)
+	if err != nil {
+		return false, err
+	}
+	return len(config.RootFS.DiffIDs) > 0 && slices.Equal(config.RootFS.DiffIDs, c.ImageInspect.RootFS.Layers), nil
+}
+
+// Recheck an outdated result by comparing the resolved images instead of the digests
+func deepCheck(ctx context.Context, c Container, result *CheckResult) {
+	imageName, _ := parseReference(result.Image)
+	same, err := sameImage(ctx, c, imageName, result.LatestDigest)
+	if err != nil {
+		log.Println("Unable to compare images:", result.Container, err)
+		return
+	}
+	if same {
+		result.IsLatest = "yes"
+		result.CurrentTags = result.LatestTags
+	}
+}
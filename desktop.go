@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/docker/docker/client"
+)
+
+// Where the daemon is looked for when neither -host, -context nor DOCKER_HOST is given, in order
+const dockerHostDetectionOrder = "DOCKER_CONTEXT or the current context of the docker CLI, " + client.DefaultDockerHost + ", Docker Desktop, rootless Docker"
+
+// Current context of the docker CLI, DOCKER_CONTEXT or the currentContext of its config
+func currentDockerContext() string {
+	if name := os.Getenv("DOCKER_CONTEXT"); name != "" {
+		return name
+	}
+	data, err := os.ReadFile(filepath.Join(dockerConfigDir(), "config.json"))
+	if err != nil {
+		return ""
+	}
+	var dockerConfig struct {
+		CurrentContext string `json:"currentContext"`
+	}
+	if json.Unmarshal(data, &dockerConfig) != nil {
+		return ""
+	}
+	return dockerConfig.CurrentContext
+}
+
+// Check that the unix socket or named pipe of host exists
+func dockerSocketExists(host string) bool {
+	if path, ok := strings.CutPrefix(host, "unix://"); ok {
+		_, err := os.Stat(path)
+		return err == nil
+	}
+	// npipe:////./pipe/docker_engine is \\.\pipe\docker_engine
+	if path, ok := strings.CutPrefix(host, "npipe://"); ok {
+		_, err := os.Stat(strings.ReplaceAll(path, "/", `\`))
+		return err == nil
+	}
+	return false
+}
+
+// Sockets of Docker Desktop and rootless Docker on this OS, by what listens on them
+func desktopDockerHosts() [][2]string {
+	home, _ := os.UserHomeDir()
+	switch runtime.GOOS {
+	case "darwin":
+		return [][2]string{{"unix://" + filepath.Join(home, ".docker", "run", "docker.sock"), "Docker Desktop"}}
+	case "windows":
+		return [][2]string{{"npipe:////./pipe/dockerDesktopLinuxEngine", "Docker Desktop"}}
+	}
+	hosts := [][2]string{{"unix://" + filepath.Join(home, ".docker", "desktop", "docker.sock"), "Docker Desktop"}}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		hosts = append(hosts, [2]string{"unix://" + filepath.Join(dir, "docker.sock"), "rootless Docker"})
+	}
+	return hosts
+}
+
+// Find the daemon of a host without DOCKER_HOST, e.g. a developer laptop running Docker Desktop,
+// and where it was found. Both are empty when nothing is listening, leaving the default of the client.
+func detectDockerHost() (host string, source string) {
+	if name := currentDockerContext(); name != "" && name != "default" {
+		if endpoint, err := LoadDockerContext(name); err == nil && dockerSocketExists(endpoint.Host) {
+			return endpoint.Host, "docker context " + name
+		}
+	}
+	if dockerSocketExists(client.DefaultDockerHost) {
+		return client.DefaultDockerHost, "default socket"
+	}
+	for _, candidate := range desktopDockerHosts() {
+		if dockerSocketExists(candidate[0]) {
+			return candidate[0], candidate[1]
+		}
+	}
+	return "", ""
+}
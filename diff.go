@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// Read a JSON report written by -output
+func readReport(path string) ([]CheckResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error while reading report: %s", err)
+	}
+	var results []CheckResult
+	err = json.Unmarshal(data, &results)
+	if err != nil {
+		return nil, fmt.Errorf("error while unmarshalling report %s: %s", path, err)
+	}
+	return results, nil
+}
+
+// Identify a result across reports, the container name on its host
+func resultKey(result CheckResult) string {
+	return containerKey(result.Container, result.Host)
+}
+
+// Change of a container between two reports: outdated, fixed, changed, added or removed
+func diffResult(old *CheckResult, new *CheckResult) string {
+	switch {
+	case old == nil:
+		return "added"
+	case new == nil:
+		return "removed"
+	case old.IsLatest != "no" && new.IsLatest == "no":
+		return "outdated"
+	case old.IsLatest == "no" && new.IsLatest == "yes":
+		return "fixed"
+	case old.IsLatest != new.IsLatest || old.Image != new.Image:
+		return "changed"
+	case new.IsLatest == "no" && old.LatestDigest != new.LatestDigest:
+		// still outdated, but behind a newer release
+		return "changed"
+	}
+	return ""
+}
+
+// diff old.json new.json: print what changed between two reports, e.g. for weekly summaries of archived reports
+func runDiff(args []string) {
+	if len(args) != 2 {
+		log.Fatal("Usage: diff old.json new.json")
+	}
+	oldResults, err := readReport(args[0])
+	if err != nil {
+		log.Fatal("Unable to read report:", err)
+	}
+	newResults, err := readReport(args[1])
+	if err != nil {
+		log.Fatal("Unable to read report:", err)
+	}
+
+	// in the order of the new report, then the removed containers
+	var keys []string
+	old := make(map[string]*CheckResult)
+	newByKey := make(map[string]*CheckResult)
+	for i := range newResults {
+		keys = append(keys, resultKey(newResults[i]))
+		newByKey[resultKey(newResults[i])] = &newResults[i]
+	}
+	for i := range oldResults {
+		old[resultKey(oldResults[i])] = &oldResults[i]
+		if newByKey[resultKey(oldResults[i])] == nil {
+			keys = append(keys, resultKey(oldResults[i]))
+		}
+	}
+
+	counts := make(map[string]int)
+	for _, key := range keys {
+		o, n := old[key], newByKey[key]
+		change := diffResult(o, n)
+		if change == "" {
+			continue
+		}
+		counts[change]++
+
+		var line string
+		switch {
+		case o == nil:
+			line = fmt.Sprintf("%s %s (%s)", key, n.Image, n.IsLatest)
+		case n == nil:
+			line = fmt.Sprintf("%s %s (%s)", key, o.Image, o.IsLatest)
+		default:
+			line = fmt.Sprintf("%s %s %s -> %s", key, n.Image, o.IsLatest, n.IsLatest)
+			if o.Image != n.Image {
+				line = fmt.Sprintf("%s %s -> %s %s -> %s", key, o.Image, n.Image, o.IsLatest, n.IsLatest)
+			}
+			if n.IsLatest == "no" && n.LatestTags != "" {
+				line += " {" + n.LatestTags + "}"
+			}
+		}
+		fmt.Printf("%10s %s\n", "["+change+"]", line)
+	}
+
+	var summary []string
+	for _, change := range []string{"outdated", "fixed", "changed", "added", "removed"} {
+		if counts[change] > 0 {
+			summary = append(summary, fmt.Sprintf("%d %s", counts[change], change))
+		}
+	}
+	if len(summary) == 0 {
+		summary = append(summary, "no changes")
+	}
+	fmt.Println(strings.Join(summary, ", "))
+}
@@ -3,39 +3,252 @@ package main
 import (
 	"context"
 	"fmt"
+	"log"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/client"
+	"github.com/docker/go-connections/tlsconfig"
 )
 
-// Use docker client API to fetch portainer list
-func GetDockerPortainerList() ([]Container, error) {
-	ctx := context.Background()
+// Number of image inspections running at the same time
+const inspectConcurrency = 8
+
+// Number of containers a run inspects at a time before checking them, all of them when 0
+var inspectBatch int
+
+// Create a docker client for endpoint, configured from the environment (DOCKER_HOST etc.) for the default endpoint
+func NewDockerClient(endpoint DockerEndpoint) (*client.Client, error) {
+	opts := []client.Opt{client.FromEnv, client.WithAPIVersionNegotiation()}
+	if endpoint.TLSDir != "" || endpoint.SkipTLSVerify {
+		options := tlsconfig.Options{InsecureSkipVerify: endpoint.SkipTLSVerify}
+		if endpoint.TLSDir != "" {
+			options.CAFile = filepath.Join(endpoint.TLSDir, "ca.pem")
+			options.CertFile = filepath.Join(endpoint.TLSDir, "cert.pem")
+			options.KeyFile = filepath.Join(endpoint.TLSDir, "key.pem")
+		}
+		tlsConfig, err := tlsconfig.Client(options)
+		if err != nil {
+			return nil, fmt.Errorf("error while loading TLS config of %s: %s", endpoint.Name, err)
+		}
+		opts = append(opts, client.WithHTTPClient(&http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}))
+	}
+	host := endpoint.Host
+	if host == "" {
+		host = os.Getenv("DOCKER_HOST")
+	}
+	// the client can't dial ssh, the requests go through the ssh command to a placeholder host instead
+	if strings.HasPrefix(host, "ssh://") {
+		dialer, err := sshDialer(host)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, client.WithHost("http://docker.example.com"), client.WithDialContext(dialer))
+	} else if endpoint.Host != "" {
+		opts = append(opts, client.WithHost(endpoint.Host))
+	} else if host == "" {
+		// e.g. Docker Desktop, which doesn't listen on the default socket
+		if detected, _ := detectDockerHost(); detected != "" {
+			opts = append(opts, client.WithHost(detected))
+		}
+	}
 
-	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	cli, err := client.NewClientWithOpts(opts...)
 	if err != nil {
 		return nil, fmt.Errorf("error while creating docker client: %s", err)
 	}
+	return cli, nil
+}
 
-	containers, err := cli.ContainerList(ctx, container.ListOptions{All: true})
+// Errors of the endpoints the last list skipped, by endpoint name, guarded by endpointErrorsMu
+// as the webhooks list the containers while a run reads them
+var (
+	endpointErrors   = make(map[string]string)
+	endpointErrorsMu sync.Mutex
+)
 
+// Copy of the errors of the endpoints the last list skipped
+func lastEndpointErrors() map[string]string {
+	endpointErrorsMu.Lock()
+	defer endpointErrorsMu.Unlock()
+	return maps.Clone(endpointErrors)
+}
+
+// Use docker client API to fetch portainer list of every endpoint, filter is empty for all containers
+func GetDockerPortainerList(filter filters.Args) ([]Container, error) {
+	containers, inspectors, err := listDockerContainers(filter)
 	if err != nil {
-		return nil, fmt.Errorf("error while listing containers: %s", err)
+		return nil, err
 	}
+	inspectContainers(inspectors, containers)
+	return containers, nil
+}
 
-	containerWithImageInfos := make([]Container, 0, len(containers))
-	for _, c := range containers {
-		img, _, err := cli.ImageInspectWithRaw(ctx, c.Image)
+// List the containers of every endpoint without their image info, and the inspectors of the endpoints by name
+func listDockerContainers(filter filters.Args) ([]Container, map[string]*endpointInspector, error) {
+	endpoints, err := DockerEndpoints()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// hosts are listed in parallel
+	lists := make([][]Container, len(endpoints))
+	listInspectors := make([]*endpointInspector, len(endpoints))
+	listErrs := make([]error, len(endpoints))
+	var wg sync.WaitGroup
+	for i, endpoint := range endpoints {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lists[i], listInspectors[i], listErrs[i] = getEndpointContainers(endpoint, filter)
+		}()
+	}
+	wg.Wait()
+
+	// an unreachable daemon only fails the run when it is the only one, the containers keep the order of the hosts
+	var containers []Container
+	inspectors := make(map[string]*endpointInspector)
+	errs := make(map[string]string)
+	for i, endpoint := range endpoints {
+		list, err := lists[i], listErrs[i]
 		if err != nil {
-			return nil, fmt.Errorf("error while inspecting image %s of container %s: %s", c.Image, c.ID, err)
+			if len(endpoints) == 1 {
+				return nil, nil, err
+			}
+			log.Println("Unable to get docker list:", endpoint.Name, err)
+			errs[endpoint.Name] = err.Error()
+			continue
+		}
+		containers = append(containers, list...)
+		inspectors[endpoint.Name] = listInspectors[i]
+	}
+	endpointErrorsMu.Lock()
+	endpointErrors = errs
+	endpointErrorsMu.Unlock()
+	return containers, inspectors, nil
+}
+
+// Client of an endpoint inspecting its containers and their images
+type endpointInspector struct {
+	cli             *client.Client
+	containerdStore bool
+	version         types.Version
+	versionOnce     sync.Once
+}
+
+// List the containers of one endpoint, to be inspected by its inspector
+func getEndpointContainers(endpoint DockerEndpoint, filter filters.Args) ([]Container, *endpointInspector, error) {
+	ctx := context.Background()
+
+	cli, err := NewDockerClient(endpoint)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	list, err := cli.ContainerList(ctx, container.ListOptions{All: true, Filters: filter})
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("error while listing containers: %s", err)
+	}
+	list = slices.DeleteFunc(list, func(c types.Container) bool {
+		return !inProjects(c.Labels)
+	})
+
+	containers := make([]Container, len(list))
+	for i, c := range list {
+		containers[i] = Container{Container: c, Endpoint: endpoint}
+	}
+	return containers, &endpointInspector{cli: cli, containerdStore: usesContainerdStore(ctx, cli)}, nil
+}
+
+// Inspect containers with the inspectors of their endpoints, each endpoint with its own pool of inspects.
+// Containers of an endpoint without inspector, e.g. read from stdin, are left as they are.
+func inspectContainers(inspectors map[string]*endpointInspector, containers []Container) {
+	byEndpoint := make(map[string][]int)
+	for i, c := range containers {
+		if _, ok := inspectors[c.Endpoint.Name]; ok {
+			byEndpoint[c.Endpoint.Name] = append(byEndpoint[c.Endpoint.Name], i)
 		}
+	}
 
-		containerWithImageInfo := Container{
-			Container:    c,
-			ImageInspect: img,
+	// a failing inspect (e.g. image removed mid-run) only affects its own container,
+	// and each endpoint dispatches its own inspects so a slow one doesn't hold back the others
+	var wg sync.WaitGroup
+	for name, indexes := range byEndpoint {
+		inspector := inspectors[name]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var endpointWg sync.WaitGroup
+			sem := make(chan struct{}, inspectConcurrency)
+			for _, i := range indexes {
+				endpointWg.Add(1)
+				sem <- struct{}{}
+				go func() {
+					defer endpointWg.Done()
+					defer func() { <-sem }()
+					inspector.inspect(&containers[i])
+				}()
+			}
+			endpointWg.Wait()
+		}()
+	}
+	wg.Wait()
+}
+
+// Fill in the state of a listed container and the info of its image
+func (e *endpointInspector) inspect(c *Container) {
+	ctx := context.Background()
+	start := time.Now()
+	defer func() { c.InspectTime = time.Since(start) }()
+
+	// the restart count and start time are only reported by inspecting the container
+	if info, err := e.cli.ContainerInspect(ctx, c.ID); err == nil && info.State != nil {
+		c.RestartCount = info.RestartCount
+		c.StartedAt, _ = time.Parse(time.RFC3339Nano, info.State.StartedAt)
+		if info.Config != nil {
+			c.ConfigImage = info.Config.Image
 		}
+	}
+	img, _, err := e.cli.ImageInspectWithRaw(ctx, c.Image)
+	// only the image ID is known then, and the platform of the daemon, whose version endpoint proxies allow
+	if isForbidden(err) {
+		e.versionOnce.Do(func() { e.version, _ = e.cli.ServerVersion(ctx) })
+		c.ImageInspect = types.ImageInspect{ID: c.ImageID, Os: e.version.Os, Architecture: e.version.Arch}
+		c.ImageIDOnly = true
+		return
+	}
+	if err != nil {
+		c.InspectError = fmt.Errorf("error while inspecting image %s of container %s: %s", c.Image, c.ID, err)
+		return
+	}
+	if e.containerdStore {
+		normalizeContainerdImage(&img)
+	}
+	c.ImageInspect = img
 
-		containerWithImageInfos = append(containerWithImageInfos, containerWithImageInfo)
+	// the daemon lists the image ID once the tag the container was created from moved to another image
+	if reference := c.ConfigImage; reference != "" && reference != c.Image && !strings.HasPrefix(reference, "sha256:") {
+		if tagged, _, err := e.cli.ImageInspectWithRaw(ctx, reference); err == nil && tagged.ID != c.ImageID {
+			c.SupersededBy = tagged.ID
+		}
 	}
-	return containerWithImageInfos, nil
+}
+
+// Drop the parts of the image info only the checks use, so the containers kept for the end of a run stay small
+func releaseImageInfo(c *Container) {
+	c.ImageInspect.ContainerConfig = nil
+	c.ImageInspect.GraphDriver = types.GraphDriverData{}
+	c.ImageInspect.RootFS = types.RootFS{}
+	c.ImageInspect.Metadata = image.Metadata{}
 }
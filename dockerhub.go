@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Number of tag pages (100 tags each, most recently pushed first) scanned on docker.io
+const dockerHubTagPages = 10
+
+type DockerHubTag struct {
+	Name   string                      `json:"name"`
+	Digest string                      `json:"digest"`
+	Images []MultiplePlatformImageInfo `json:"images"`
+	Pushed time.Time                   `json:"tag_last_pushed"`
+}
+
+type DockerHubTagPage struct {
+	Next    *string        `json:"next"`
+	Results []DockerHubTag `json:"results"`
+}
+
+// Number of containers running each docker.io repository in the current run, by namespace/name
+var dockerHubRepositoryUses = make(map[string]int)
+
+// Count the containers of each docker.io repository, to batch the lookups of shared repositories
+func countDockerHubRepositories(containers []Container) {
+	dockerHubRepositoryUses = make(map[string]int)
+	for _, c := range containers {
+		imageName, _ := parseReference(c.Image)
+		if registry, namespace, name := parseImage(imageName); registry == "docker.io" {
+			dockerHubRepositoryUses[namespace+"/"+name]++
+		}
+	}
+}
+
+// Get a page of the recently pushed tags of a docker.io repository
+func getDockerHubTagPage(ctx context.Context, image string, page int) (DockerHubTagPage, error) {
+	_, namespace, name := parseImage(image)
+
+	var tagPage DockerHubTagPage
+	url := fmt.Sprintf("https://registry.hub.docker.com/v2/repositories/%s/%s/tags?page_size=100&page=%d", namespace, name, page)
+	resp, err := dockerHubFetch(ctx, image, url)
+	if err != nil {
+		return tagPage, err
+	}
+	body := resp.Body
+
+	err = json.Unmarshal(body, &tagPage)
+	if err != nil {
+		return tagPage, fmt.Errorf("server error while unmarshalling body: %s", err)
+	}
+	return tagPage, nil
+}
+
+// List the recently pushed tags of a docker.io repository
+func GetDockerHubTags(ctx context.Context, image string) ([]DockerHubTag, error) {
+	var tags []DockerHubTag
+	for page := 1; page <= dockerHubTagPages; page++ {
+		tagPage, err := getDockerHubTagPage(ctx, image, page)
+		if err != nil {
+			return nil, err
+		}
+
+		tags = append(tags, tagPage.Results...)
+		if tagPage.Next == nil {
+			break
+		}
+	}
+	return tags, nil
+}
+
+// Find a tag of a repository shared by several containers in the first page of its tag listing,
+// which answers the lookups of all its tags with one request
+func listedDockerHubTag(ctx context.Context, image string, tag string) (ImageInfo, bool) {
+	_, namespace, name := parseImage(image)
+	if dockerHubRepositoryUses[namespace+"/"+name] < 2 {
+		return ImageInfo{}, false
+	}
+
+	tagPage, err := getDockerHubTagPage(ctx, image, 1)
+	if err != nil {
+		return ImageInfo{}, false
+	}
+	for _, t := range tagPage.Results {
+		if t.Name == tag && (len(t.Images) > 0 || t.Digest != "") {
+			return ImageInfo{Digest: t.Digest, MultiplePlatformImageInfoList: t.Images, Pushed: t.Pushed}, true
+		}
+	}
+	return ImageInfo{}, false
+}
+
+// Names of the tags whose index digest or one of whose platform digests is digest,
+// so an image pulled by its platform digest is also found
+func tagsWithDigest(tags []DockerHubTag, digest string) []string {
+	var names []string
+	if digest == "" {
+		return names
+	}
+	for _, tag := range tags {
+		if tag.Digest == digest || slices.ContainsFunc(tag.Images, func(image MultiplePlatformImageInfo) bool {
+			return image.Digest == digest
+		}) {
+			names = append(names, tag.Name)
+		}
+	}
+	return names
+}
+
+// Find the digest of image among the local repo digests, e.g. nginx@sha256:...
+func localDigest(repoDigests []string, image string) string {
+	for _, repoDigest := range repoDigests {
+		if digest, ok := strings.CutPrefix(repoDigest, image+"@"); ok {
+			return digest
+		}
+	}
+	return ""
+}
+
+var (
+	dockerHubUsername string
+	dockerHubToken    string // password or personal access token
+
+	// API resolving the tags of Docker Hub images: hub for the Hub REST API, registry for manifest HEAD requests
+	dockerHubAPI string
+
+	// JWT of the Docker Hub session, logged in on the first private repository
+	dockerHubJWT string
+	// Repositories only visible to the session, their tag listings are authenticated too
+	dockerHubPrivate = make(map[string]bool)
+	// guards the session and the private repositories, the checks of containers share them
+	dockerHubMu sync.Mutex
+)
+
+// Private Docker Hub repository without credentials to see it with
+var errPrivateNeedsAuth = fmt.Errorf("%w: private repository", errAuthRequired)
+
+// Log in to Docker Hub, returning the JWT of the session
+// ref: https://docs.docker.com/reference/api/hub/latest/#tag/authentication-api/operation/AuthCreateAccessToken
+func dockerHubLogin() (string, error) {
+	dockerHubMu.Lock()
+	defer dockerHubMu.Unlock()
+	if dockerHubJWT != "" {
+		return dockerHubJWT, nil
+	}
+	body, err := json.Marshal(map[string]string{"username": dockerHubUsername, "password": dockerHubToken})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest("POST", "https://hub.docker.com/v2/users/login", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("error while creating request: %s", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	setRequestHeaders(req)
+	resp, err := (&http.Client{Transport: transport}).Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error while logging in to Docker Hub: %w", errors.Join(err, errRegistryUnavailable))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%w: %d while logging in to Docker Hub as %s", errAuthRequired, resp.StatusCode, dockerHubUsername)
+	}
+	var session struct {
+		Token string `json:"token"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&session)
+	if err != nil || session.Token == "" {
+		return "", fmt.Errorf("error while reading Docker Hub session: %v", err)
+	}
+	dockerHubJWT = session.Token
+	return dockerHubJWT, nil
+}
+
+// Headers of the requests for a docker.io repository, authenticated for the private ones
+func dockerHubHeaders(image string) http.Header {
+	_, namespace, name := parseImage(image)
+	dockerHubMu.Lock()
+	defer dockerHubMu.Unlock()
+	if !dockerHubPrivate[namespace+"/"+name] || dockerHubJWT == "" {
+		return nil
+	}
+	return http.Header{"Authorization": {"Bearer " + dockerHubJWT}}
+}
+
+// Get a Docker Hub URL of image anonymously, retrying with the session when the repository is hidden:
+// Hub answers 401 or 403 for private repositories, or 404 as if they didn't exist
+func dockerHubFetch(ctx context.Context, image string, url string) (HTTPResponse, error) {
+	resp, err := httpFetch(ctx, url, dockerHubHeaders(image))
+	if err != nil || !slices.Contains([]int{http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound}, resp.StatusCode) {
+		return resp, err
+	}
+	_, namespace, name := parseImage(image)
+	dockerHubMu.Lock()
+	private := dockerHubPrivate[namespace+"/"+name]
+	dockerHubMu.Unlock()
+	if private {
+		return resp, nil
+	}
+	if dockerHubUsername == "" || dockerHubToken == "" {
+		if resp.StatusCode != http.StatusNotFound {
+			return resp, fmt.Errorf("%w: %d while getting %s from Docker Hub, set -dockerhub_username and -dockerhub_token", errPrivateNeedsAuth, resp.StatusCode, image)
+		}
+		return resp, nil
+	}
+
+	jwt, err := dockerHubLogin()
+	if err != nil {
+		return resp, err
+	}
+	// the anonymous answer is kept under the same URL
+	cacheMu.Lock()
+	delete(cache.HTTPCache, url)
+	cacheMu.Unlock()
+	authenticated, err := httpFetch(ctx, url, http.Header{"Authorization": {"Bearer " + jwt}})
+	if err != nil {
+		return resp, err
+	}
+	if authenticated.StatusCode == http.StatusOK {
+		dockerHubMu.Lock()
+		dockerHubPrivate[namespace+"/"+name] = true
+		dockerHubMu.Unlock()
+	}
+	return authenticated, nil
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/docker/docker/api/types/filters"
+)
+
+// Outcome of a doctor check, with how to fix it when it failed
+type doctorResult struct {
+	status string // ok, warning or failed
+	name   string
+	detail string
+	fix    string
+}
+
+// doctor: check Docker connectivity, registry reachability, tokens and file permissions, printing fixes
+func runDoctor(args []string) {
+	if len(args) != 0 {
+		log.Fatal("Usage: doctor")
+	}
+	resetCache()
+
+	var results []doctorResult
+	results = append(results, doctorDocker()...)
+	results = append(results, doctorRegistries()...)
+	results = append(results, doctorFiles()...)
+
+	failed := false
+	for _, r := range results {
+		line := fmt.Sprintf("%10s %s", "["+r.status+"]", r.name)
+		if r.detail != "" {
+			line += ": " + r.detail
+		}
+		fmt.Println(line)
+		if r.fix != "" && r.status != "ok" {
+			fmt.Printf("%10s %s\n", "fix:", r.fix)
+		}
+		failed = failed || r.status == "failed"
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func doctorDocker() []doctorResult {
+	fix := "mount the docker socket (-v /var/run/docker.sock:/var/run/docker.sock:ro), or set DOCKER_HOST, -host or -context"
+	endpoints, err := DockerEndpoints()
+	if err != nil {
+		return []doctorResult{{"failed", "docker endpoints", err.Error(), "check the -host and -context flags"}}
+	}
+
+	var results []doctorResult
+	if len(dockerHosts) == 0 && len(dockerContexts) == 0 {
+		results = append(results, doctorDockerHost())
+	}
+	for _, endpoint := range endpoints {
+		name := "docker"
+		if endpoint.Name != "" {
+			name += " " + endpoint.Name
+		}
+		cli, err := NewDockerClient(endpoint)
+		if err != nil {
+			results = append(results, doctorResult{"failed", name, err.Error(), fix})
+			continue
+		}
+		ping, err := cli.Ping(context.Background())
+		cli.Close()
+		if err != nil {
+			results = append(results, doctorResult{"failed", name, err.Error(), fix})
+			continue
+		}
+		results = append(results, doctorResult{"ok", name, "API " + ping.APIVersion, ""})
+	}
+
+	// images without a repo digest can never be compared, a common cause of results without a status
+	containers, err := GetDockerPortainerList(filters.NewArgs())
+	if err != nil {
+		return results
+	}
+	var noDigest, idOnly []string
+	for _, c := range containers {
+		if c.ImageIDOnly {
+			idOnly = append(idOnly, strings.TrimPrefix(c.Names[0], "/"))
+			continue
+		}
+		if c.InspectError == nil && len(c.ImageInspect.RepoDigests) == 0 {
+			noDigest = append(noDigest, strings.TrimPrefix(c.Names[0], "/"))
+		}
+	}
+	if len(noDigest) > 0 {
+		results = append(results, doctorResult{"warning", "repo digests", fmt.Sprintf("%d of %d containers run images built or loaded locally: %s", len(noDigest), len(containers), strings.Join(noDigest, ", ")),
+			"pull the images from their registry, or label local builds with their base image"})
+	} else {
+		results = append(results, doctorResult{"ok", "repo digests", fmt.Sprintf("%d containers", len(containers)), ""})
+	}
+	if len(idOnly) > 0 {
+		results = append(results, doctorResult{"warning", "image inspect", fmt.Sprintf("forbidden, %d containers are only compared by image ID", len(idOnly)),
+			"allow image inspects in the socket proxy, e.g. IMAGES=1 of tecnativa/docker-socket-proxy"})
+	}
+	return results
+}
+
+// Report where the default daemon is found, and the order it is looked for in
+func doctorDockerHost() doctorResult {
+	if host := os.Getenv("DOCKER_HOST"); host != "" {
+		return doctorResult{"ok", "docker host", host + " from DOCKER_HOST", ""}
+	}
+	host, source := detectDockerHost()
+	if host == "" {
+		return doctorResult{"warning", "docker host", "no socket found, looked for " + dockerHostDetectionOrder,
+			"start the docker daemon or Docker Desktop, or set DOCKER_HOST, -host or -context"}
+	}
+	return doctorResult{"ok", "docker host", host + " from " + source + " (looked for " + dockerHostDetectionOrder + ")", ""}
+}
+
+// Check that url answers with one of the expected status codes
+func doctorFetch(ctx context.Context, name string, url string, headers http.Header, fix string, expected ...int) (doctorResult, HTTPResponse) {
+	resp, err := httpFetch(ctx, url, headers)
+	if err != nil {
+		return doctorResult{"failed", name, err.Error(), "check the network, -proxy and the scheme of the registry (\"scheme\": \"http\" for plain HTTP)"}, resp
+	}
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return doctorResult{"failed", name, "rate limited", "log in to raise the rate limit, or check less often"}, resp
+	case !slices.Contains(expected, resp.StatusCode):
+		return doctorResult{"failed", name, fmt.Sprintf("unexpected status %d", resp.StatusCode), fix}, resp
+	}
+	return doctorResult{"ok", name, fmt.Sprintf("status %d", resp.StatusCode), ""}, resp
+}
+
+func doctorRegistries() []doctorResult {
+	hub, _ := doctorFetch(context.Background(), "docker hub", "https://registry.hub.docker.com/v2/repositories/library/alpine/tags/latest", nil, "", http.StatusOK)
+	github, _ := doctorFetch(context.Background(), "github api", "https://api.github.com/", nil, "", http.StatusOK)
+	results := []doctorResult{hub, github}
+
+	if ghcr_token == "" {
+		results = append(results, doctorResult{"warning", "ghcr_token", "not set, ghcr.io images can't be checked", "set -ghcr_token to a token with read:packages, or login ghcr.io"})
+	} else {
+		headers := make(http.Header)
+		headers.Set("Authorization", "Bearer "+ghcr_token)
+		r, resp := doctorFetch(context.Background(), "ghcr_token", "https://api.github.com/user", headers, "the token is invalid or expired, create a new one", http.StatusOK)
+		// classic tokens list their scopes, fine-grained tokens don't
+		if scopes := resp.Header.Get("X-OAuth-Scopes"); r.status == "ok" && scopes != "" && !strings.Contains(scopes, "read:packages") {
+			r = doctorResult{"failed", "ghcr_token", "lacks read:packages, it has: " + scopes, "add the read:packages scope to the token"}
+		} else if r.status == "ok" {
+			r.detail = "valid"
+		}
+		results = append(results, r)
+	}
+
+	for _, registry := range sortedKeys(config.Registries) {
+		rc := config.Registries[registry]
+		if isRegistryPattern(registry) {
+			continue
+		}
+		name := "registry " + registry
+		switch rc.Type {
+		case "harbor", "registry":
+			// Harbor accepts the basic auth of robot accounts on the registry API, which answers 401 without
+			headers := make(http.Header)
+			expected := []int{http.StatusOK, http.StatusUnauthorized}
+			if rc.Type == "harbor" && rc.Username != "" {
+				headers.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(rc.Username+":"+rc.Password)))
+				expected = []int{http.StatusOK}
+			}
+			r, _ := doctorFetch(context.Background(), name, registryURL(registry)+"/v2/", headers, "check the username and password of the registry in the config", expected...)
+			results = append(results, r)
+		case "artifactory":
+			base := strings.TrimSuffix(rc.URL, "/")
+			if base == "" {
+				base = registryURL(registry) + "/artifactory"
+			}
+			r, _ := doctorFetch(context.Background(), name, base+"/api/system/ping", artifactoryHeaders(rc), "check the token, API key or url of the registry in the config", http.StatusOK)
+			results = append(results, r)
+		default:
+			results = append(results, doctorResult{"failed", name, fmt.Sprintf("unknown type %q", rc.Type), "set the type to harbor, artifactory or registry"})
+		}
+	}
+	return results
+}
+
+// Check that a file can be written in its directory
+func doctorWritable(name string, path string, fix string) doctorResult {
+	dir := filepath.Dir(path)
+	err := os.MkdirAll(dir, 0o755)
+	if err == nil {
+		var f *os.File
+		f, err = os.CreateTemp(dir, ".doctor-*")
+		if err == nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}
+	if err != nil {
+		return doctorResult{"failed", name, err.Error(), fix}
+	}
+	return doctorResult{"ok", name, path, ""}
+}
+
+func doctorFiles() []doctorResult {
+	var results []doctorResult
+	for _, file := range []struct{ name, path, flag string }{
+		{"state file", statePath, "-state"},
+		{"http cache", httpCachePath, "-http_cache"},
+		{"history", historyPath, "-history"},
+		{"audit log", auditPath, "-audit_log"},
+	} {
+		if file.path == "" {
+			continue
+		}
+		results = append(results, doctorWritable(file.name, file.path, fmt.Sprintf("mount a writable volume, or set %s to a writable path", file.flag)))
+	}
+	return results
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Fill colors of the statuses in dot graphs, by group of statuses
+var dotColors = map[string]string{
+	"outdated": "salmon",
+	"unknown":  "khaki",
+	"uptodate": "palegreen",
+}
+
+func dotColor(status string) string {
+	for group, color := range dotColors {
+		if slices.Contains(statusGroups[group], status) {
+			return color
+		}
+	}
+	return "lightgray"
+}
+
+// Quote s as a DOT string
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+// Render results as a Graphviz graph of the stacks, their containers and the images they share, colored by status
+func renderDOT(results []CheckResult) ([]byte, error) {
+	var b strings.Builder
+	b.WriteString("digraph \"docker-check-is-latest\" {\n")
+	b.WriteString("\trankdir=LR;\n")
+	b.WriteString("\tnode [shape=box, style=filled];\n")
+
+	// containers by stack, in the order of the results, the containers of no stack last
+	var stacks []string
+	byStack := make(map[string][]CheckResult)
+	for _, result := range results {
+		stack := result.Stack
+		if stack != "" && result.Host != "" {
+			stack += "@" + result.Host
+		}
+		if _, ok := byStack[stack]; !ok && stack != "" {
+			stacks = append(stacks, stack)
+		}
+		byStack[stack] = append(byStack[stack], result)
+	}
+	containerNode := func(result CheckResult) string {
+		return fmt.Sprintf("\t%s [label=%s, fillcolor=%s];\n",
+			dotQuote("container "+result.Host+result.Container), dotQuote(strings.TrimPrefix(result.Container, "/")+"\n"+tr(result.IsLatest)), dotColor(result.IsLatest))
+	}
+	for i, stack := range stacks {
+		fmt.Fprintf(&b, "\tsubgraph cluster_%d {\n\t\tlabel=%s;\n", i, dotQuote(stack))
+		for _, result := range byStack[stack] {
+			b.WriteString("\t" + containerNode(result))
+		}
+		b.WriteString("\t}\n")
+	}
+	for _, result := range byStack[""] {
+		b.WriteString(containerNode(result))
+	}
+
+	// an image is as outdated as the most outdated container running it
+	var images []string
+	imageStatus := make(map[string]CheckResult)
+	for _, result := range results {
+		current, ok := imageStatus[result.Image]
+		if !ok {
+			images = append(images, result.Image)
+		}
+		if !ok || statusRank(result.IsLatest) < statusRank(current.IsLatest) {
+			imageStatus[result.Image] = result
+		}
+	}
+	for _, image := range images {
+		result := imageStatus[image]
+		label := image
+		if result.LatestTags != "" && result.IsLatest != "yes" {
+			label += "\n-> " + result.LatestTags
+		}
+		fmt.Fprintf(&b, "\t%s [shape=ellipse, label=%s, fillcolor=%s];\n", dotQuote("image "+image), dotQuote(label), dotColor(result.IsLatest))
+	}
+	for _, result := range results {
+		fmt.Fprintf(&b, "\t%s -> %s;\n", dotQuote("container "+result.Host+result.Container), dotQuote("image "+result.Image))
+	}
+	b.WriteString("}\n")
+	return []byte(b.String()), nil
+}
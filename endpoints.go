@@ -0,0 +1,113 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Repeatable string flag
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+var (
+	dockerHosts    stringList
+	dockerContexts stringList
+)
+
+// A docker daemon to check, from -host or -context
+type DockerEndpoint struct {
+	Name          string // host or context name, empty for the daemon configured by the environment
+	Host          string
+	TLSDir        string // directory with ca.pem, cert.pem and key.pem of a context
+	SkipTLSVerify bool
+}
+
+// Metadata of a docker context
+// ref: https://github.com/docker/cli/blob/master/cli/context/store/metadatastore.go
+type dockerContextMeta struct {
+	Name      string `json:"Name"`
+	Endpoints map[string]struct {
+		Host          string `json:"Host"`
+		SkipTLSVerify bool   `json:"SkipTLSVerify"`
+	} `json:"Endpoints"`
+}
+
+// Docker CLI config directory, ~/.docker unless DOCKER_CONFIG is set
+func dockerConfigDir() string {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".docker"
+	}
+	return filepath.Join(home, ".docker")
+}
+
+// Resolve a named docker context from the context store of the docker CLI
+func LoadDockerContext(name string) (DockerEndpoint, error) {
+	// "default" is the daemon configured by the environment
+	if name == "default" {
+		return DockerEndpoint{Name: name}, nil
+	}
+
+	// contexts are stored in a directory named by the sha256 of their name
+	sum := sha256.Sum256([]byte(name))
+	id := hex.EncodeToString(sum[:])
+	contextsDir := filepath.Join(dockerConfigDir(), "contexts")
+
+	data, err := os.ReadFile(filepath.Join(contextsDir, "meta", id, "meta.json"))
+	if err != nil {
+		return DockerEndpoint{}, fmt.Errorf("error while reading docker context %s: %s", name, err)
+	}
+
+	var meta dockerContextMeta
+	err = json.Unmarshal(data, &meta)
+	if err != nil {
+		return DockerEndpoint{}, fmt.Errorf("error while unmarshalling docker context %s: %s", name, err)
+	}
+	docker, ok := meta.Endpoints["docker"]
+	if !ok {
+		return DockerEndpoint{}, fmt.Errorf("docker context %s has no docker endpoint", name)
+	}
+
+	endpoint := DockerEndpoint{Name: name, Host: docker.Host, SkipTLSVerify: docker.SkipTLSVerify}
+	tlsDir := filepath.Join(contextsDir, "tls", id, "docker")
+	if _, err := os.Stat(tlsDir); err == nil {
+		endpoint.TLSDir = tlsDir
+	}
+	return endpoint, nil
+}
+
+// The docker daemons to check, the environment's one when neither -host nor -context is given
+func DockerEndpoints() ([]DockerEndpoint, error) {
+	var endpoints []DockerEndpoint
+	for _, host := range dockerHosts {
+		endpoints = append(endpoints, DockerEndpoint{Name: host, Host: host})
+	}
+	for _, name := range dockerContexts {
+		endpoint, err := LoadDockerContext(name)
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+
+	if len(endpoints) == 0 {
+		endpoints = append(endpoints, DockerEndpoint{})
+	}
+	return endpoints, nil
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"slices"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+)
+
+// Verify the cosign signature of a file, e.g. an allowlist, against the -cosign_key
+func VerifyBlobSignature(path string, signaturePath string) error {
+	if cosignKey == "" {
+		return fmt.Errorf("no public key to verify %s, set -cosign_key", path)
+	}
+	out, err := exec.Command("cosign", "verify-blob", "--key", cosignKey, "--signature", signaturePath, path).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("error while verifying %s: %s %s", path, err, string(out))
+	}
+	return nil
+}
+
+// Read an allowlist of the approved digests by image name, after verifying its signature
+func LoadAllowlist(path string, signaturePath string) (map[string][]string, error) {
+	err := VerifyBlobSignature(path, signaturePath)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error while reading allowlist: %s", err)
+	}
+	var allowlist map[string][]string
+	err = json.Unmarshal(data, &allowlist)
+	if err != nil {
+		return nil, fmt.Errorf("error while unmarshalling allowlist: %s", err)
+	}
+
+	// the same image may be listed as nginx and docker.io/library/nginx
+	approved := make(map[string][]string)
+	for image, digests := range allowlist {
+		imageName, _ := parseReference(image)
+		approved[imageName] = append(approved[imageName], digests...)
+	}
+	return approved, nil
+}
+
+// enforce [-signature allowlist.json.sig] [-stop] [allowlist.json]: report the containers running digests
+// missing from the signed allowlist, stopping them with -stop
+func runEnforce(args []string) {
+	fs := flag.NewFlagSet("enforce", flag.ExitOnError)
+	signaturePath := fs.String("signature", "", "Cosign signature of the allowlist, <allowlist>.sig by default")
+	stop := fs.Bool("stop", false, "Stop the containers running unapproved digests")
+	fs.Parse(args)
+
+	allowlistPath := "allowlist.json"
+	if fs.NArg() > 0 {
+		allowlistPath = fs.Arg(0)
+	}
+	if *signaturePath == "" {
+		*signaturePath = allowlistPath + ".sig"
+	}
+
+	allowlist, err := LoadAllowlist(allowlistPath, *signaturePath)
+	if err != nil {
+		log.Fatal("Unable to load allowlist:", err)
+	}
+
+	containers, err := GetDockerPortainerList(filters.NewArgs())
+	if err != nil {
+		log.Fatal("Unable to get docker list:", err)
+	}
+
+	runID = newRunID()
+	unapproved := false
+	for _, c := range containers {
+		imageName, _ := parseReference(localReference(c))
+		if c.InspectError != nil || c.ImageIDOnly {
+			// the digest of the image is unknown, it is neither approved nor stopped
+			log.Printf("%12s %s %s", "[unknown]", c.Names[0], imageName)
+			if c.InspectError != nil {
+				log.Println("Unable to inspect image:", c.Names[0], c.InspectError)
+			}
+			unapproved = true
+			continue
+		}
+		digest := localDigest(c.ImageInspect.RepoDigests, imageName)
+		if digest == "" {
+			// locally built images have no registry digest, they are never approved but not stopped either
+			log.Printf("%12s %s %s (no registry digest)", "[unknown]", c.Names[0], imageName)
+			unapproved = true
+			continue
+		}
+		status := "approved"
+		if !slices.Contains(allowlist[imageName], digest) {
+			status = "unapproved"
+			unapproved = true
+		}
+		log.Printf("%12s %s %s@%s", "["+status+"]", c.Names[0], imageName, digest)
+
+		if status == "approved" || !*stop || isSelf(c) || c.State != "running" {
+			continue
+		}
+		entry := AuditEntry{Action: "stop", Container: c.Names[0], Host: c.Endpoint.Name, Image: c.Image, OldDigest: digest, Outcome: "stopped"}
+		err := stopContainer(c)
+		if err != nil {
+			log.Println("Unable to stop container:", c.Names[0], err)
+			entry.Outcome, entry.Error = "failed", err.Error()
+		} else {
+			log.Printf("%12s %s", "[stopped]", c.Names[0])
+		}
+		RecordAudit(entry)
+	}
+
+	if unapproved {
+		os.Exit(1)
+	}
+}
+
+func stopContainer(c Container) error {
+	if err := checkWritable("stop " + c.Names[0]); err != nil {
+		return err
+	}
+	cli, err := NewDockerClient(c.Endpoint)
+	if err != nil {
+		return fmt.Errorf("unable to create docker client: %s", err)
+	}
+	defer cli.Close()
+
+	err = cli.ContainerStop(context.Background(), c.ID, container.StopOptions{})
+	if err != nil {
+		return fmt.Errorf("error while stopping %s: %s", c.Names[0], err)
+	}
+	return nil
+}
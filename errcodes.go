@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+)
+
+// Causes of failures with a stable error code, wrapped by the errors of the lookups
+var (
+	errUnsupportedRegistry = errors.New("unsupported registry")
+	errAuthRequired        = errors.New("authentication required")
+	errRateLimited         = errors.New("rate limited")
+	errPlatformMismatch    = errors.New("no image for the platform")
+)
+
+// Stable codes of the causes, for automation to branch on, first match wins
+var errorCodes = []struct {
+	err  error
+	code string
+}{
+	{errUnsupportedRegistry, "REGISTRY_UNSUPPORTED"},
+	{errAuthRequired, "AUTH_REQUIRED"},
+	{errRateLimited, "RATE_LIMITED"},
+	{errNotFound, "TAG_NOT_FOUND"},
+	{errRegistryUnavailable, "REGISTRY_UNAVAILABLE"},
+	{errPlatformMismatch, "PLATFORM_MISMATCH"},
+	{errCheckTimeout, "CHECK_TIMEOUT"},
+	{errCheckPanic, "CHECK_PANIC"},
+}
+
+// Status of a result whose registry lookup failed with err, unknown when the cause has none
+func lookupStatus(err error) string {
+	switch {
+	case errors.Is(err, errNotFound):
+		return "not-found"
+	case errors.Is(err, errRegistryUnavailable):
+		return "registry-unavailable"
+	case errors.Is(err, errPrivateNeedsAuth):
+		return "private-needs-auth"
+	}
+	return "unknown"
+}
+
+// Error code of err, UNKNOWN when its cause has none
+func errorCode(err error) string {
+	for _, c := range errorCodes {
+		if errors.Is(err, c.err) {
+			return c.code
+		}
+	}
+	return "UNKNOWN"
+}
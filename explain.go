@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/docker/docker/api/types/filters"
+)
+
+// Container whose check is traced step by step by -explain
+var explainContainer string
+
+// Whether the steps of the current check are printed
+var explaining bool
+
+// Print a step of the check of -explain
+func explain(step string, format string, args ...any) {
+	if explaining {
+		fmt.Printf("  %-18s %s\n", step+":", fmt.Sprintf(format, args...))
+	}
+}
+
+// Join values for a step, - when there are none
+func explainList(values []string) string {
+	if len(values) == 0 {
+		return "-"
+	}
+	return strings.Join(values, ", ")
+}
+
+// Check a single container and print how its status was reached, e.g. when a "no" is disputed
+func runExplain(name string) {
+	name = strings.TrimPrefix(name, "/")
+	// the name filter of docker matches substrings
+	containers, err := GetDockerPortainerList(filters.NewArgs(filters.Arg("name", name)))
+	if err != nil {
+		log.Fatal("Unable to get docker list: ", err)
+	}
+	i := slices.IndexFunc(containers, func(c Container) bool {
+		return slices.Contains(c.Names, "/"+name)
+	})
+	if i < 0 {
+		log.Fatal("No container named ", name)
+	}
+	container := containers[i]
+
+	resetCache()
+	fmt.Printf("Container %s", name)
+	if container.Endpoint.Name != "" {
+		fmt.Printf(" on %s", container.Endpoint.Name)
+	}
+	fmt.Printf(" (%.19s)\n", container.ID)
+	explaining = true
+	result := checkContainer(context.Background(), container)
+	explaining = false
+
+	fmt.Printf("Verdict: %s\n", result.IsLatest)
+	if result.Error != "" {
+		fmt.Printf("  %-18s %s (%s)\n", "error:", result.Error, result.ErrorCode)
+	}
+	if result.Warning != "" {
+		fmt.Printf("  %-18s %s\n", "warning:", result.Warning)
+	}
+	if result.IsLatest != "yes" {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Latest results of a host, this one or a reporting agent
+type fleetHost struct {
+	CheckedAt time.Time     `json:"checked_at"`
+	Results   []CheckResult `json:"results"`
+}
+
+var (
+	fleetMu sync.RWMutex
+	fleet   = make(map[string]fleetHost)
+
+	aggregator bool
+	fleetPath  string
+	pullURLs   stringList // web dashboards of agents polled by the aggregator
+)
+
+// Name of this host in the fleet
+func localHost() string {
+	host, _ := os.Hostname()
+	return host
+}
+
+// Default location of the fleet file, next to the state file
+func defaultFleetPath() string {
+	return filepath.Join(filepath.Dir(defaultStatePath()), "fleet.json")
+}
+
+// Read the fleet file, a missing file is an empty fleet
+func LoadFleet(path string) (map[string]fleetHost, error) {
+	f := make(map[string]fleetHost)
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return f, nil
+	} else if err != nil {
+		return f, fmt.Errorf("error while reading fleet: %s", err)
+	}
+
+	err = json.Unmarshal(data, &f)
+	if err != nil {
+		return f, fmt.Errorf("error while unmarshalling fleet: %s", err)
+	}
+	return f, nil
+}
+
+func SaveFleet(path string, f map[string]fleetHost) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error while marshalling fleet: %s", err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return fmt.Errorf("error while creating fleet directory: %s", err)
+	}
+	return writeFileAtomic(path, data)
+}
+
+// Store the latest results of host and stream the results of its last check to the subscribers
+func publishResults(host string, checked []CheckResult, latest []CheckResult) {
+	fleetMu.Lock()
+	fleet[host] = fleetHost{CheckedAt: timestamp(), Results: latest}
+	if aggregator {
+		err := SaveFleet(fleetPath, fleet)
+		if err != nil {
+			log.Println("Unable to save fleet:", err)
+		}
+	}
+	fleetMu.Unlock()
+
+	streamResults(host, checked)
+}
+
+// Per-host summary of the fleet
+type FleetHostSummary struct {
+	Host      string        `json:"host"`
+	CheckedAt time.Time     `json:"checked_at"`
+	Outdated  int           `json:"outdated"`
+	UpToDate  int           `json:"up_to_date"`
+	Unknown   int           `json:"unknown"`
+	Results   []CheckResult `json:"results"`
+}
+
+// Summaries of every host, by name
+func fleetSummary() []FleetHostSummary {
+	fleetMu.RLock()
+	defer fleetMu.RUnlock()
+
+	summaries := make([]FleetHostSummary, 0, len(fleet))
+	for name, host := range fleet {
+		outdated, upToDate, unknown := countResults(host.Results)
+		summaries = append(summaries, FleetHostSummary{
+			Host:      name,
+			CheckedAt: host.CheckedAt,
+			Outdated:  len(outdated),
+			UpToDate:  upToDate,
+			Unknown:   unknown,
+			Results:   host.Results,
+		})
+	}
+	slices.SortFunc(summaries, func(a, b FleetHostSummary) int { return strings.Compare(a.Host, b.Host) })
+	return summaries
+}
+
+// Fetch the latest results from the web dashboard API of an agent
+func pullResults(ctx context.Context, agentURL string) (string, []CheckResult, error) {
+	u, err := url.Parse(agentURL)
+	if err != nil {
+		return "", nil, fmt.Errorf("error while parsing %s: %s", agentURL, err)
+	}
+
+	// the agent traffic bypasses httpFetch, its responses are neither cached nor in the metadata file
+	req, err := http.NewRequestWithContext(ctx, "GET", strings.TrimSuffix(agentURL, "/")+"/api/v1/results", nil)
+	if err != nil {
+		return "", nil, fmt.Errorf("error while creating request: %s", err)
+	}
+	setRequestHeaders(req)
+	client := &http.Client{
+		Transport: transport,
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", nil, fmt.Errorf("error while pulling %s: %s", agentURL, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", nil, fmt.Errorf("error while reading body: %s", err)
+	}
+	if resp.StatusCode != 200 {
+		return "", nil, fmt.Errorf("error while pulling %s: %d %s", agentURL, resp.StatusCode, string(body))
+	}
+
+	var results []CheckResult
+	err = json.Unmarshal(body, &results)
+	if err != nil {
+		return "", nil, fmt.Errorf("server error while unmarshalling body: %s", err)
+	}
+	return u.Hostname(), results, nil
+}
+
+// Collect the results of agents, pushed through the gRPC API or pulled from their dashboards, without checking local containers
+func runAggregator() {
+	var err error
+	fleet, err = LoadFleet(fleetPath)
+	if err != nil {
+		log.Fatal("Unable to load fleet:", err)
+	}
+
+	if listenAddr != "" {
+		go func() {
+			log.Fatal("Unable to serve:", Serve(listenAddr))
+		}()
+	}
+	if grpcAddr != "" {
+		go func() {
+			log.Fatal("Unable to serve gRPC:", ServeGRPC(grpcAddr))
+		}()
+	}
+
+	every := interval
+	if every <= 0 {
+		every = 5 * time.Minute
+	}
+	for {
+		for _, agentURL := range pullURLs {
+			host, results, err := pullResults(context.Background(), agentURL)
+			if err != nil {
+				log.Println("Unable to pull results:", err)
+				continue
+			}
+			publishResults(host, results, results)
+			log.Printf("%10s %s %d containers", "[pulled]", host, len(results))
+		}
+		time.Sleep(every)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Escape the message of a GitHub Actions workflow command, and its properties with property set
+// ref: https://github.com/actions/toolkit/blob/main/packages/core/src/command.ts
+func escapeWorkflowCommand(s string, property bool) string {
+	s = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+	if property {
+		s = strings.NewReplacer(":", "%3A", ",", "%2C").Replace(s)
+	}
+	return s
+}
+
+// Append to the file of a GitHub Actions environment file variable, e.g. GITHUB_OUTPUT, when it is set
+func appendWorkflowFile(env string, data string) error {
+	path := os.Getenv(env)
+	if path == "" {
+		return nil
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("error while opening %s: %s", env, err)
+	}
+	defer f.Close()
+	_, err = f.WriteString(data)
+	if err != nil {
+		return fmt.Errorf("error while writing %s: %s", env, err)
+	}
+	return nil
+}
+
+// Render results as GitHub Actions workflow commands: a warning annotation per outdated container and an error
+// per failed check, with the Markdown table as step summary and outdated_count, outdated and errors_count as outputs
+// ref: https://docs.github.com/en/actions/writing-workflows/choosing-what-your-workflow-does/workflow-commands-for-github-actions
+func renderGHA(results []CheckResult) ([]byte, error) {
+	var b strings.Builder
+	var outdated []string
+	errorsCount := 0
+	for _, result := range results {
+		name := strings.TrimPrefix(result.Container, "/")
+		if result.Host != "" {
+			name += "@" + result.Host
+		}
+		switch {
+		case result.IsLatest == "no" || result.IsLatest == "base-outdated":
+			outdated = append(outdated, name)
+			message := fmt.Sprintf("%s runs %s, latest tags: %s", name, result.Image, result.LatestTags)
+			if result.ChangelogURL != "" {
+				message += "\nRelease notes: " + result.ChangelogURL
+			}
+			fmt.Fprintf(&b, "::warning title=%s::%s\n", escapeWorkflowCommand("Outdated image "+result.Image, true), escapeWorkflowCommand(message, false))
+		case result.IsLatest == "superseded-locally":
+			outdated = append(outdated, name)
+			fmt.Fprintf(&b, "::warning title=%s::%s\n", escapeWorkflowCommand("Superseded image "+result.Image, true), escapeWorkflowCommand(name+": "+result.Warning, false))
+		case result.IsLatest == "repo-moved":
+			outdated = append(outdated, name)
+			fmt.Fprintf(&b, "::warning title=%s::%s\n", escapeWorkflowCommand("Moved repository "+result.Image, true), escapeWorkflowCommand(name+": "+result.Warning, false))
+		case result.Error != "":
+			errorsCount++
+			fmt.Fprintf(&b, "::error title=%s::%s\n", escapeWorkflowCommand("Unable to check "+name, true), escapeWorkflowCommand(result.Error, false))
+		}
+	}
+
+	summary, err := renderMarkdown(results)
+	if err != nil {
+		return nil, err
+	}
+	err = appendWorkflowFile("GITHUB_STEP_SUMMARY", string(summary)+"\n")
+	if err != nil {
+		return nil, err
+	}
+	err = appendWorkflowFile("GITHUB_OUTPUT", fmt.Sprintf("outdated_count=%d\noutdated=%s\nerrors_count=%d\n", len(outdated), strings.Join(outdated, ","), errorsCount))
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(&b, "%d of %d containers outdated\n", len(outdated), len(results))
+	return []byte(b.String()), nil
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Turn a failed GitHub packages API response into an actionable error
+// ref: https://docs.github.com/en/rest/using-the-rest-api/troubleshooting-the-rest-api
+func ghcrError(resp HTTPResponse, image string) error {
+	var body struct {
+		Message string `json:"message"`
+	}
+	json.Unmarshal(resp.Body, &body)
+	message := body.Message
+	if message == "" {
+		message = strings.TrimSpace(string(resp.Body))
+	}
+
+	switch {
+	case resp.StatusCode == 401:
+		return fmt.Errorf("%w: ghcr_token is invalid or expired for %s (GitHub: %s)", errAuthRequired, image, message)
+	case resp.Header.Get("X-GitHub-SSO") != "":
+		// e.g. "required; url=https://github.com/orgs/org/sso?authorization_request=..."
+		_, url, _ := strings.Cut(resp.Header.Get("X-GitHub-SSO"), "url=")
+		return fmt.Errorf("%w: ghcr_token is not authorized for the SAML SSO of the organization of %s, authorize it at %s", errAuthRequired, image, url)
+	case resp.Header.Get("X-RateLimit-Remaining") == "0":
+		return fmt.Errorf("%w: GitHub API rate limit exceeded while getting %s, resets at unix time %s", errRateLimited, image, resp.Header.Get("X-RateLimit-Reset"))
+	case resp.StatusCode == 404 && ghcr_token == "":
+		// GitHub hides the packages a request can't read
+		return fmt.Errorf("%w: no ghcr_token to see package %s with (GitHub: %s)", errAuthRequired, image, message)
+	case (resp.StatusCode == 403 || resp.StatusCode == 404) && resp.Header.Get("X-OAuth-Scopes") != "" && !strings.Contains(resp.Header.Get("X-OAuth-Scopes"), "read:packages"):
+		// classic tokens list their scopes, fine-grained tokens don't
+		return fmt.Errorf("%w: ghcr_token lacks read:packages for %s, it has: %s", errAuthRequired, image, resp.Header.Get("X-OAuth-Scopes"))
+	case resp.StatusCode == 403:
+		return fmt.Errorf("%w: ghcr_token is not allowed to read %s, check that it has read:packages (or the Packages read permission for fine-grained tokens) (GitHub: %s)", errAuthRequired, image, message)
+	case resp.StatusCode == 404:
+		return fmt.Errorf("%w: package %s, or ghcr_token cannot see it (GitHub: %s)", errNotFound, image, message)
+	}
+	return fmt.Errorf("error %d while getting %s (GitHub: %s)", resp.StatusCode, image, message)
+}
+
+// Owner and package name of a ghcr.io image, the name of nested images has slashes, e.g. org and app/component
+func ghcrPackage(image string) (owner string, name string) {
+	_, path, _ := strings.Cut(image, "ghcr.io/")
+	owner, name, _ = strings.Cut(path, "/")
+	return owner, name
+}
+
+// URL and headers listing the versions of a ghcr.io package
+// doc: https://docs.github.com/zh/rest/packages/packages?apiVersion=2022-11-28#list-package-versions-for-a-package-owned-by-an-organization
+func ghcrVersionsRequest(image string) (string, http.Header, error) {
+	// the recorded metadata was fetched with the token of the connected host
+	if ghcr_token == "" && metadata == nil {
+		return "", nil, fmt.Errorf("missing ghcr_token")
+	}
+	owner, name := ghcrPackage(image)
+	versionsURL := fmt.Sprintf("https://api.github.com/orgs/%s/packages/container/%s/versions?per_page=100", owner, url.PathEscape(name))
+
+	headers := make(http.Header)
+	headers.Set("Accept", "application/vnd.github+json")
+	headers.Set("Authorization", "Bearer "+ghcr_token)
+	headers.Set("X-GitHub-Api-Version", "2022-11-28")
+	return versionsURL, headers, nil
+}
+
+// Number of ghcr.io packages whose versions are fetched at once before the checks
+var ghcrConcurrency int
+
+// Fetch the first page of versions of the ghcr.io packages of containers concurrently, into the cache of the run.
+// GitHub's GraphQL API doesn't serve container packages, so the REST calls are batched instead of being made one
+// by one as the containers are checked.
+func prefetchGHCRVersions(ctx context.Context, containers []Container) {
+	if ghcrConcurrency <= 1 || ghcr_token == "" || metadata != nil {
+		return
+	}
+	var urls []string
+	var headers http.Header
+	for _, c := range containers {
+		imageName, _ := parseReference(c.Image)
+		if registry, _, _ := parseImage(imageName); registry != "ghcr.io" {
+			continue
+		}
+		url, h, err := ghcrVersionsRequest(imageName)
+		if err != nil || slices.Contains(urls, url) {
+			continue
+		}
+		urls, headers = append(urls, url), h
+	}
+	if len(urls) < 2 {
+		return
+	}
+
+	// failed pages aren't cached, the checks report their errors
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, ghcrConcurrency)
+	for _, url := range urls {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer wg.Done()
+			httpFetch(ctx, url, headers)
+			<-slots
+		}()
+	}
+	wg.Wait()
+}
+
+// List the tags of the recent versions of a ghcr.io package
+func GetGHCRTags(ctx context.Context, image string) ([]string, error) {
+	url, headers, err := ghcrVersionsRequest(image)
+	if err != nil {
+		return nil, err
+	}
+
+	var tags []string
+	err = walkGHCRVersions(ctx, url, headers, image, func(v GHCRVersion) bool {
+		tags = append(tags, v.Metadata.Container.Tags...)
+		return false
+	})
+	if err != nil && !errors.Is(err, errGHCRMaxPages) {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// Returned when the searched versions exceed -ghcr_max_pages
+var errGHCRMaxPages = errors.New("too many pages of versions")
+
+// Walk the versions listed at url page by page, most recent first, until visit returns true
+func walkGHCRVersions(ctx context.Context, url string, headers http.Header, image string, visit func(v GHCRVersion) bool) error {
+	next := url
+	for page := 1; next != ""; page++ {
+		if page > ghcrMaxPages {
+			return errGHCRMaxPages
+		}
+
+		resp, err := httpFetch(ctx, next, headers)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode >= 400 {
+			return ghcrError(resp, image)
+		}
+
+		var resVersions []GHCRVersion
+		err = json.Unmarshal(resp.Body, &resVersions)
+		if err != nil {
+			return fmt.Errorf("server error while unmarshalling body: %s", err)
+		}
+
+		for _, v := range resVersions {
+			if visit(v) {
+				return nil
+			}
+		}
+
+		if len(resVersions) == 0 {
+			break
+		}
+		next = nextLink(resp.Header)
+	}
+	return nil
+}
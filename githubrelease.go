@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Container label naming the GitHub repository the image is released from, e.g. github.com/owner/repo
+const labelSource = "is-latest.source"
+
+// GitHub repository of the releases of c from its label or the config entry of its image, owner/repo or empty
+func releaseSource(c Container, imageName string) string {
+	source := config.ReleaseSources[imageName]
+	if label, ok := c.Labels[labelSource]; ok && label != "" {
+		source = label
+	}
+	source = strings.TrimPrefix(strings.TrimPrefix(source, "https://"), "http://")
+	repo, ok := strings.CutPrefix(strings.TrimSuffix(source, "/"), "github.com/")
+	if !ok || strings.Count(repo, "/") != 1 {
+		return ""
+	}
+	return repo
+}
+
+// Headers of the requests to the GitHub REST API
+func githubHeaders() http.Header {
+	headers := http.Header{"Accept": {"application/vnd.github+json"}}
+	// GitHub tokens are also accepted by the REST API, with a higher rate limit
+	if ghcr_token != "" {
+		headers.Set("Authorization", "Bearer "+ghcr_token)
+	}
+	return headers
+}
+
+// Tag of the latest release of a GitHub repository, e.g. v1.2.3 of owner/repo
+// ref: https://docs.github.com/en/rest/releases/releases#get-the-latest-release
+func githubLatestRelease(ctx context.Context, repo string) (string, error) {
+	url := "https://api.github.com/repos/" + repo + "/releases/latest"
+	r, err := httpFetch(ctx, url, githubHeaders())
+	if err != nil {
+		return "", err
+	}
+	switch r.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return "", fmt.Errorf("%w: no release of %s", errNotFound, repo)
+	case http.StatusForbidden, http.StatusTooManyRequests:
+		return "", fmt.Errorf("%w: %d while getting the latest release of %s", errRateLimited, r.StatusCode, repo)
+	default:
+		return "", fmt.Errorf("error %d from %s", r.StatusCode, url)
+	}
+	var release struct {
+		TagName string `json:"tag_name"`
+	}
+	err = json.Unmarshal(r.Body, &release)
+	if err != nil {
+		return "", fmt.Errorf("error while unmarshalling release: %s", err)
+	}
+	return release.TagName, nil
+}
+
+// How far version current is behind latest: major, minor or patch, digest for the same version, empty when it can't tell
+func versionSeverity(current string, latest string) string {
+	return numbersSeverity(versionNumbers(current), versionNumbers(latest))
+}
+
+// Replace the status of result by comparing the version label of the image of c with the latest release of repo,
+// for images whose registry lags the releases or isn't supported. A failed comparison keeps the verdict of the registry.
+func checkGitHubRelease(ctx context.Context, c Container, repo string, result *CheckResult) {
+	current := ""
+	if m := localOCIMetadata(c.ImageInspect); m != nil {
+		current = m.Version
+	}
+	if current == "" {
+		result.Error, result.ErrorCode = "the image has no org.opencontainers.image.version label to compare with the releases of "+repo, ""
+		return
+	}
+	latest, err := githubLatestRelease(ctx, repo)
+	if err != nil {
+		result.Error, result.ErrorCode = err.Error(), errorCode(err)
+		return
+	}
+
+	result.Error, result.ErrorCode, result.Severity = "", "", ""
+	result.VersionCheck = &VersionCheck{Current: current, Latest: latest, Source: "release"}
+	result.ChangelogURL = "https://github.com/" + repo + "/releases"
+	// a label ahead of the latest release, e.g. a pre-release, isn't outdated either
+	if strings.TrimPrefix(current, "v") == strings.TrimPrefix(latest, "v") ||
+		slices.Compare(versionNumbers(current), versionNumbers(latest)) >= 0 {
+		result.IsLatest = "yes"
+		return
+	}
+	result.IsLatest = "no"
+	result.Severity = versionSeverity(current, latest)
+}
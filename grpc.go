@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"docker-check-is-latest/api"
+
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/go-connections/tlsconfig"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	grpcmetadata "google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	grpcAddr   string
+	grpcReport string // address of the central instance agents report to
+	grpcToken  string
+
+	// certificate and key of the server with -grpc, of the client with -grpc_report,
+	// and the CA verifying the other side
+	grpcTLSCert string
+	grpcTLSKey  string
+	grpcTLSCA   string
+)
+
+var (
+	subscribersMu sync.Mutex
+	subscribers   = make(map[chan *api.Result]struct{})
+
+	reportConn *grpc.ClientConn
+)
+
+func toProtoResult(host string, r CheckResult) *api.Result {
+	if r.Host != "" {
+		host = r.Host
+	}
+	vulnerabilities := make(map[string]int32)
+	for severity, n := range r.Vulnerabilities {
+		vulnerabilities[severity] = int32(n)
+	}
+	return &api.Result{
+		Host:            host,
+		Container:       r.Container,
+		Image:           r.Image,
+		IsLatest:        r.IsLatest,
+		LatestTags:      r.LatestTags,
+		CurrentTags:     r.CurrentTags,
+		CurrentDigest:   r.CurrentDigest,
+		LatestDigest:    r.LatestDigest,
+		CompareTag:      r.CompareTag,
+		ChangelogUrl:    r.ChangelogURL,
+		Signature:       r.Signature,
+		Vulnerabilities: vulnerabilities,
+		Update:          r.Update,
+		Warning:         r.Warning,
+		Error:           r.Error,
+	}
+}
+
+func fromProtoResult(r *api.Result) CheckResult {
+	var vulnerabilities map[string]int
+	if len(r.Vulnerabilities) > 0 {
+		vulnerabilities = make(map[string]int)
+		for severity, n := range r.Vulnerabilities {
+			vulnerabilities[severity] = int(n)
+		}
+	}
+	return CheckResult{
+		Host:            r.Host,
+		Container:       r.Container,
+		Image:           r.Image,
+		IsLatest:        r.IsLatest,
+		LatestTags:      r.LatestTags,
+		CurrentTags:     r.CurrentTags,
+		CurrentDigest:   r.CurrentDigest,
+		LatestDigest:    r.LatestDigest,
+		CompareTag:      r.CompareTag,
+		ChangelogURL:    r.ChangelogUrl,
+		Signature:       r.Signature,
+		Vulnerabilities: vulnerabilities,
+		Update:          r.Update,
+		Warning:         r.Warning,
+		Error:           r.Error,
+	}
+}
+
+// Stream the results of the last check of host to the subscribers
+func streamResults(host string, checked []CheckResult) {
+	subscribersMu.Lock()
+	defer subscribersMu.Unlock()
+	for ch := range subscribers {
+		for _, result := range checked {
+			select {
+			case ch <- toProtoResult(host, result):
+			default: // drop results for subscribers that don't keep up
+			}
+		}
+	}
+}
+
+type checkerServer struct {
+	api.UnimplementedCheckerServer
+}
+
+func (checkerServer) RunCheck(ctx context.Context, req *api.RunCheckRequest) (*api.RunCheckResponse, error) {
+	if aggregator {
+		return nil, status.Error(codes.FailedPrecondition, "an aggregator doesn't check containers")
+	}
+
+	filter := filters.NewArgs()
+	for _, name := range req.Containers {
+		filter.Add("name", "^/?"+regexp.QuoteMeta(strings.TrimPrefix(name, "/"))+"$")
+	}
+
+	err := run(filter, 0)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	resp := &api.RunCheckResponse{}
+	for _, result := range latestResults() {
+		if len(req.Containers) == 0 || slices.Contains(req.Containers, result.Container) || slices.Contains(req.Containers, strings.TrimPrefix(result.Container, "/")) {
+			resp.Results = append(resp.Results, toProtoResult(localHost(), result))
+		}
+	}
+	return resp, nil
+}
+
+func (checkerServer) StreamResults(req *api.StreamResultsRequest, stream grpc.ServerStreamingServer[api.Result]) error {
+	ch := make(chan *api.Result, 100)
+	subscribersMu.Lock()
+	subscribers[ch] = struct{}{}
+	subscribersMu.Unlock()
+	defer func() {
+		subscribersMu.Lock()
+		delete(subscribers, ch)
+		subscribersMu.Unlock()
+	}()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case result := <-ch:
+			err := stream.Send(result)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (checkerServer) ListHosts(ctx context.Context, req *api.ListHostsRequest) (*api.ListHostsResponse, error) {
+	resp := &api.ListHostsResponse{}
+	for _, host := range fleetSummary() {
+		resp.Hosts = append(resp.Hosts, &api.Host{
+			Name:       host.Host,
+			CheckedAt:  host.CheckedAt.Unix(),
+			Containers: int32(len(host.Results)),
+			Outdated:   int32(host.Outdated),
+		})
+	}
+	return resp, nil
+}
+
+func (checkerServer) Report(ctx context.Context, req *api.ReportRequest) (*api.ReportResponse, error) {
+	if req.Host == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing host")
+	}
+	results := make([]CheckResult, 0, len(req.Results))
+	for _, result := range req.Results {
+		results = append(results, fromProtoResult(result))
+	}
+	publishResults(req.Host, results, results)
+	return &api.ReportResponse{}, nil
+}
+
+// Check if addr only listens on or dials the local host, e.g. localhost:7070
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return host == "localhost" || (ip != nil && ip.IsLoopback())
+}
+
+// Check the gRPC flags: RunCheck can update containers, so the API needs a token unless only local processes reach it,
+// and the token isn't sent over the network in plain text
+func validateGRPC() error {
+	if (grpcTLSCert == "") != (grpcTLSKey == "") {
+		return errors.New("-grpc_tls_cert and -grpc_tls_key must be given together")
+	}
+	if grpcAddr != "" && grpcToken == "" && !isLoopbackAddr(grpcAddr) {
+		return fmt.Errorf("-grpc %s needs -grpc_token, or must listen on localhost", grpcAddr)
+	}
+	if grpcReport != "" && grpcToken != "" && grpcTLSCA == "" && grpcTLSCert == "" && !isLoopbackAddr(grpcReport) {
+		return fmt.Errorf("-grpc_report %s would send -grpc_token in plain text, set -grpc_tls_ca or -grpc_tls_cert", grpcReport)
+	}
+	return nil
+}
+
+// Check the bearer token of incoming calls when -grpc_token is set
+func checkToken(ctx context.Context) error {
+	if grpcToken == "" {
+		return nil
+	}
+	md, _ := grpcmetadata.FromIncomingContext(ctx)
+	// compared in constant time, so the time of a rejection doesn't tell how much of the token matched
+	for _, value := range md.Get("authorization") {
+		if subtle.ConstantTimeCompare([]byte(value), []byte("Bearer "+grpcToken)) == 1 {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "invalid token")
+}
+
+// Serve the gRPC API on addr
+func ServeGRPC(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("error while listening on %s: %s", addr, err)
+	}
+
+	var options []grpc.ServerOption
+	if grpcTLSCert != "" {
+		// with a CA, the agents have to present a certificate it signed
+		tlsOptions := tlsconfig.Options{CertFile: grpcTLSCert, KeyFile: grpcTLSKey, CAFile: grpcTLSCA}
+		if grpcTLSCA != "" {
+			tlsOptions.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+		tlsConfig, err := tlsconfig.Server(tlsOptions)
+		if err != nil {
+			return fmt.Errorf("error while loading gRPC TLS config: %s", err)
+		}
+		options = append(options, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	server := grpc.NewServer(append(options,
+		grpc.UnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			if err := checkToken(ctx); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := checkToken(ss.Context()); err != nil {
+				return err
+			}
+			return handler(srv, ss)
+		}),
+	)...)
+	api.RegisterCheckerServer(server, checkerServer{})
+	return server.Serve(listener)
+}
+
+// Report the latest results of this host to the central instance at -grpc_report
+func ReportResults(results []CheckResult) error {
+	if reportConn == nil {
+		creds := insecure.NewCredentials()
+		if grpcTLSCA != "" || grpcTLSCert != "" {
+			// without a CA, the certificate of the central instance is verified against the system roots
+			tlsConfig, err := tlsconfig.Client(tlsconfig.Options{CAFile: grpcTLSCA, CertFile: grpcTLSCert, KeyFile: grpcTLSKey})
+			if err != nil {
+				return fmt.Errorf("error while loading gRPC TLS config: %s", err)
+			}
+			creds = credentials.NewTLS(tlsConfig)
+		}
+		conn, err := grpc.NewClient(grpcReport, grpc.WithTransportCredentials(creds))
+		if err != nil {
+			return fmt.Errorf("error while connecting to %s: %s", grpcReport, err)
+		}
+		reportConn = conn
+	}
+
+	host := localHost()
+	req := &api.ReportRequest{Host: host}
+	for _, result := range results {
+		req.Results = append(req.Results, toProtoResult(host, result))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if grpcToken != "" {
+		ctx = grpcmetadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+grpcToken)
+	}
+	_, err := api.NewCheckerClient(reportConn).Report(ctx, req)
+	if err != nil {
+		return fmt.Errorf("error while reporting to %s: %s", grpcReport, err)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Most pages of artifacts listed per Harbor repository
+var harborMaxPages int
+
+// Artifact of a Harbor repository
+// doc: https://goharbor.io/docs/main/build-customize-contribute/configure-swagger/
+type HarborArtifact struct {
+	Digest   string    `json:"digest"`
+	PushTime time.Time `json:"push_time"`
+	Tags     []struct {
+		Name string `json:"name"`
+	} `json:"tags"`
+}
+
+// Split a Harbor image into its project and repository, e.g. harbor.example.com/library/team/app -> library, team/app
+func harborRepository(image string) (project string, repository string, err error) {
+	parts := strings.SplitN(image, "/", 3)
+	if len(parts) < 3 {
+		return "", "", fmt.Errorf("no project in Harbor image %s", image)
+	}
+	return parts[1], parts[2], nil
+}
+
+// List the artifacts of a Harbor repository with their tags, 100 per page, most recently pushed first
+func GetHarborArtifacts(ctx context.Context, image string) ([]HarborArtifact, error) {
+	registry, _, _ := parseImage(image)
+	rc := registryConfig(registry)
+	project, repository, err := harborRepository(image)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := make(http.Header)
+	headers.Set("Accept", "application/json")
+	// robot accounts authenticate with basic auth, e.g. robot$project+ci
+	if rc.Username != "" {
+		headers.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(rc.Username+":"+rc.Password)))
+	}
+
+	var artifacts []HarborArtifact
+	for page := 1; page <= harborMaxPages; page++ {
+		// slashes of nested repositories are escaped twice
+		u := fmt.Sprintf("%s/api/v2.0/projects/%s/repositories/%s/artifacts?with_tag=true&page_size=100&page=%d&sort=-push_time",
+			registryURL(registry), url.PathEscape(project), url.PathEscape(url.PathEscape(repository)), page)
+		resp, err := httpFetch(ctx, u, headers)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+			return nil, fmt.Errorf("%w: access to %s denied by Harbor (%d), check the robot account in the registries config", errAuthRequired, image, resp.StatusCode)
+		case resp.StatusCode == http.StatusNotFound:
+			return nil, fmt.Errorf("%w: Harbor repository %s", errNotFound, image)
+		case resp.StatusCode >= 400:
+			return nil, fmt.Errorf("error while listing artifacts of %s: %d %s", image, resp.StatusCode, string(resp.Body))
+		}
+
+		var pageArtifacts []HarborArtifact
+		err = json.Unmarshal(resp.Body, &pageArtifacts)
+		if err != nil {
+			return nil, fmt.Errorf("server error while unmarshalling body: %s", err)
+		}
+		artifacts = append(artifacts, pageArtifacts...)
+		if len(pageArtifacts) < 100 {
+			break
+		}
+	}
+	return artifacts, nil
+}
+
+func (a HarborArtifact) tagNames() []string {
+	names := make([]string, 0, len(a.Tags))
+	for _, tag := range a.Tags {
+		names = append(names, tag.Name)
+	}
+	return names
+}
+
+// Find the artifact of a Harbor image carrying tag, or one of digests when given
+func GetHarborInfo(ctx context.Context, image string, tag string, digests []string) (ImageInfo, error) {
+	artifacts, err := GetHarborArtifacts(ctx, image)
+	if err != nil {
+		return ImageInfo{}, err
+	}
+	for _, a := range artifacts {
+		if (digests != nil && slices.Contains(digests, image+"@"+a.Digest)) ||
+			(digests == nil && slices.Contains(a.tagNames(), tag)) {
+			return ImageInfo{Digest: a.Digest, Tags: a.tagNames(), Pushed: a.PushTime}, nil
+		}
+	}
+	return ImageInfo{}, fmt.Errorf("%w: %s:%s in any artifact", errNotFound, image, tag)
+}
+
+// List the tags of a Harbor repository
+func GetHarborTags(ctx context.Context, image string) ([]string, error) {
+	artifacts, err := GetHarborArtifacts(ctx, image)
+	if err != nil {
+		return nil, err
+	}
+	var tags []string
+	for _, a := range artifacts {
+		tags = append(tags, a.tagNames()...)
+	}
+	return tags, nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+var historyPath string
+
+const historySchema = `
+CREATE TABLE IF NOT EXISTS results (
+	id             INTEGER PRIMARY KEY AUTOINCREMENT,
+	checked_at     TEXT NOT NULL, -- RFC3339 in UTC
+	container      TEXT NOT NULL,
+	image          TEXT NOT NULL,
+	is_latest      TEXT NOT NULL,
+	current_digest TEXT NOT NULL,
+	latest_digest  TEXT NOT NULL,
+	current_tags   TEXT NOT NULL,
+	latest_tags    TEXT NOT NULL,
+	update_status  TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS results_container_checked_at ON results (container, checked_at);
+CREATE TABLE IF NOT EXISTS audit (
+	id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	time       TEXT NOT NULL, -- RFC3339 in UTC
+	run_id     TEXT NOT NULL,
+	actor      TEXT NOT NULL,
+	action     TEXT NOT NULL,
+	container  TEXT NOT NULL,
+	host       TEXT NOT NULL,
+	image      TEXT NOT NULL,
+	old_digest TEXT NOT NULL,
+	new_digest TEXT NOT NULL,
+	outcome    TEXT NOT NULL,
+	error      TEXT NOT NULL
+);
+`
+
+// Open the SQLite history database, creating its schema if needed
+func OpenHistory(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("error while opening history: %s", err)
+	}
+
+	_, err = db.Exec(historySchema)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error while creating history schema: %s", err)
+	}
+	return db, nil
+}
+
+// Append the results of a run to the history
+func RecordHistory(path string, results []CheckResult, checkedAt time.Time) error {
+	db, err := OpenHistory(path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("error while starting transaction: %s", err)
+	}
+	defer tx.Rollback()
+
+	for _, result := range results {
+		_, err = tx.Exec(`INSERT INTO results (checked_at, container, image, is_latest, current_digest, latest_digest, current_tags, latest_tags, update_status)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			checkedAt.UTC().Format(time.RFC3339), containerKey(result.Container, result.Host), result.Image, result.IsLatest,
+			result.CurrentDigest, result.LatestDigest, result.CurrentTags, result.LatestTags, result.Update)
+		if err != nil {
+			return fmt.Errorf("error while recording history: %s", err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("error while committing history: %s", err)
+	}
+	return nil
+}
+
+// Append an action to the audit table, rows are never updated or deleted
+func recordAuditHistory(path string, entry AuditEntry) error {
+	db, err := OpenHistory(path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`INSERT INTO audit (time, run_id, actor, action, container, host, image, old_digest, new_digest, outcome, error)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		entry.Time.UTC().Format(time.RFC3339), entry.RunID, entry.Actor, entry.Action, entry.Container, entry.Host,
+		entry.Image, entry.OldDigest, entry.NewDigest, entry.Outcome, entry.Error)
+	if err != nil {
+		return fmt.Errorf("error while recording audit entry: %s", err)
+	}
+	return nil
+}
+
+type HistoryEntry struct {
+	CheckedAt     string `json:"checked_at"`
+	Image         string `json:"image"`
+	IsLatest      string `json:"is_latest"`
+	CurrentDigest string `json:"current_digest"`
+	LatestDigest  string `json:"latest_digest"`
+}
+
+// Status changes of a container on host, oldest first
+func QueryHistory(path string, container string, host string) ([]HistoryEntry, error) {
+	db, err := OpenHistory(path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`SELECT checked_at, image, is_latest, current_digest, latest_digest FROM results
+		WHERE container = ? ORDER BY checked_at, id`, containerKey(container, host))
+	if err != nil {
+		return nil, fmt.Errorf("error while querying history: %s", err)
+	}
+	defer rows.Close()
+
+	changes := []HistoryEntry{}
+	for rows.Next() {
+		var e HistoryEntry
+		err = rows.Scan(&e.CheckedAt, &e.Image, &e.IsLatest, &e.CurrentDigest, &e.LatestDigest)
+		if err != nil {
+			return nil, fmt.Errorf("error while reading history: %s", err)
+		}
+
+		if n := len(changes); n > 0 && changes[n-1].IsLatest == e.IsLatest && changes[n-1].CurrentDigest == e.CurrentDigest {
+			continue
+		}
+		changes = append(changes, e)
+	}
+	return changes, rows.Err()
+}
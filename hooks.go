@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Container labels overriding the configured update hooks
+const (
+	labelPreUpdate  = "is-latest.pre-update"
+	labelPostUpdate = "is-latest.post-update"
+)
+
+// Find the hook of a container for event ("pre-update" or "post-update")
+func containerHook(c Container, event string) string {
+	switch event {
+	case "pre-update":
+		if hook, ok := c.Labels[labelPreUpdate]; ok {
+			return hook
+		}
+		return config.Update.PreUpdate
+	case "post-update":
+		if hook, ok := c.Labels[labelPostUpdate]; ok {
+			return hook
+		}
+		return config.Update.PostUpdate
+	}
+	return ""
+}
+
+// Run a hook, which is either a webhook URL receiving a JSON POST or a shell command
+func RunHook(hook string, event string, c Container) error {
+	if hook == "" {
+		return nil
+	}
+
+	if strings.HasPrefix(hook, "http://") || strings.HasPrefix(hook, "https://") {
+		body, err := json.Marshal(map[string]string{
+			"event":     event,
+			"container": c.Names[0],
+			"image":     c.Image,
+		})
+		if err != nil {
+			return fmt.Errorf("error while marshalling hook payload: %s", err)
+		}
+
+		req, err := http.NewRequest("POST", hook, bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("error while creating request: %s", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return sendNotification(req)
+	}
+
+	cmd := exec.Command("sh", "-c", hook)
+	cmd.Env = append(os.Environ(),
+		"IS_LATEST_EVENT="+event,
+		"IS_LATEST_CONTAINER="+c.Names[0],
+		"IS_LATEST_IMAGE="+c.Image,
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("error while running %s hook: %s %s", event, err, string(out))
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"html/template"
+	"os"
+	"strings"
+	"time"
+)
+
+// Template of -format html, overriding the embedded one
+var htmlTemplatePath string
+
+//go:embed report.html.tmpl
+var defaultHTMLTemplate string
+
+// Data of the HTML report template
+type HTMLReport struct {
+	CheckedAt time.Time
+	RunID     string
+	Partial   string         // what couldn't be checked, see errorSummary
+	Counts    map[string]int // containers by status
+	Results   []CheckResult
+}
+
+// Render results as a standalone HTML page, for emailing or publishing to a static site
+func renderHTML(results []CheckResult) ([]byte, error) {
+	text := defaultHTMLTemplate
+	if htmlTemplatePath != "" {
+		data, err := os.ReadFile(htmlTemplatePath)
+		if err != nil {
+			return nil, fmt.Errorf("error while reading template: %s", err)
+		}
+		text = string(data)
+	}
+	tmpl, err := template.New("report").Funcs(template.FuncMap{
+		"containerName": func(result CheckResult) string {
+			name := strings.TrimPrefix(result.Container, "/")
+			if result.Host != "" {
+				name += "@" + result.Host
+			}
+			return name
+		},
+		"join": strings.Join,
+		"tr":   tr,
+		"lang": langTag,
+	}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("error while parsing template: %s", err)
+	}
+
+	report := HTMLReport{CheckedAt: timestamp(), RunID: runID, Partial: errorSummary(results), Counts: make(map[string]int), Results: results}
+	for _, result := range results {
+		report.Counts[result.IsLatest]++
+	}
+	var b bytes.Buffer
+	err = tmpl.Execute(&b, report)
+	if err != nil {
+		return nil, fmt.Errorf("error while executing template: %s", err)
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// A response kept between runs with its validators, to revalidate it with a conditional request
+type CachedResponse struct {
+	ETag         string      `json:"etag,omitempty"`
+	LastModified string      `json:"last_modified,omitempty"`
+	StatusCode   int         `json:"status_code"`
+	Header       http.Header `json:"header"`
+	Body         []byte      `json:"body"`
+}
+
+var (
+	httpCachePath string
+	httpCache     = make(map[string]CachedResponse) // by URL
+)
+
+// Default location of the HTTP cache, e.g. ~/.cache/docker-check-is-latest/http-cache.json
+func defaultHTTPCachePath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "docker-check-is-latest", "http-cache.json")
+}
+
+// Read the HTTP cache file, a missing file is an empty cache
+func LoadHTTPCache(path string) (map[string]CachedResponse, error) {
+	c := make(map[string]CachedResponse)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	} else if err != nil {
+		return c, fmt.Errorf("error while reading http cache: %s", err)
+	}
+	err = json.Unmarshal(data, &c)
+	if err != nil {
+		return make(map[string]CachedResponse), fmt.Errorf("error while unmarshalling http cache: %s", err)
+	}
+	return c, nil
+}
+
+func SaveHTTPCache(path string, c map[string]CachedResponse) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("error while marshalling http cache: %s", err)
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return fmt.Errorf("error while creating http cache directory: %s", err)
+	}
+	return writeFileAtomic(path, data)
+}
+
+// Add the validators of the cached response of url to the request headers
+func setConditionalHeaders(url string, headers http.Header) {
+	cached, ok := httpCache[url]
+	if !ok {
+		return
+	}
+	if cached.ETag != "" {
+		headers.Set("If-None-Match", cached.ETag)
+	}
+	if cached.LastModified != "" {
+		headers.Set("If-Modified-Since", cached.LastModified)
+	}
+}
+
+// Drop the cached responses the run didn't request, e.g. of removed images
+func pruneHTTPCache() {
+	for url := range httpCache {
+		if _, ok := cache.HTTPCache[url]; !ok {
+			delete(httpCache, url)
+		}
+	}
+}
+
+// Keep a response with validators for the next runs
+func storeValidatedResponse(url string, r HTTPResponse) {
+	etag, lastModified := r.Header.Get("ETag"), r.Header.Get("Last-Modified")
+	if r.StatusCode != http.StatusOK || (etag == "" && lastModified == "") {
+		return
+	}
+	httpCache[url] = CachedResponse{ETag: etag, LastModified: lastModified, StatusCode: r.StatusCode, Header: r.Header, Body: r.Body}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Language of the reports and notifications: en, a locale of locales/, or a JSON message catalog file
+var lang string
+
+//go:embed locales/*.json
+var locales embed.FS
+
+// Translations of the English messages and status words in -lang, nil for en
+var catalog map[string]string
+
+// Load the message catalog of -lang
+func loadCatalog() error {
+	if lang == "" || lang == "en" {
+		catalog = nil
+		return nil
+	}
+	var data []byte
+	var err error
+	if strings.HasSuffix(lang, ".json") {
+		data, err = os.ReadFile(lang)
+	} else {
+		data, err = locales.ReadFile("locales/" + lang + ".json")
+	}
+	if err != nil {
+		return fmt.Errorf("error while reading catalog of %s: %s", lang, err)
+	}
+	err = json.Unmarshal(data, &catalog)
+	if err != nil {
+		return fmt.Errorf("error while parsing catalog of %s: %s", lang, err)
+	}
+	return nil
+}
+
+// Translate an English message or status word, which stays as is when the catalog has no translation
+func tr(message string) string {
+	if t, ok := catalog[message]; ok {
+		return t
+	}
+	return message
+}
+
+// Translate a format and print the args with it
+func trf(format string, args ...any) string {
+	return fmt.Sprintf(tr(format), args...)
+}
+
+// Language tag of the reports, e.g. for the lang attribute of the HTML report, empty for a catalog file
+func langTag() string {
+	switch {
+	case lang == "":
+		return "en"
+	case strings.HasSuffix(lang, ".json"):
+		return ""
+	}
+	return lang
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Full reference of image, e.g. docker.io/library/postgres:16 for postgres:16
+func fullReference(image string) string {
+	imageName, tag := parseReference(image)
+	registry, namespace, name := parseImage(imageName)
+	if registry == "docker.io" && !strings.HasPrefix(imageName, "docker.io/") {
+		imageName = registry + "/" + namespace + "/" + name
+	}
+	return imageName + ":" + tag
+}
+
+// Compile a glob pattern where * matches any characters, slashes included, and ? matches one character
+func compileGlob(pattern string) (*regexp.Regexp, error) {
+	expr := regexp.QuoteMeta(pattern)
+	expr = strings.ReplaceAll(expr, `\*`, ".*")
+	expr = strings.ReplaceAll(expr, `\?`, ".")
+	return regexp.Compile("^" + expr + "$")
+}
+
+// Check if image matches one of the ignore patterns of the config
+func isIgnored(image string) bool {
+	reference := fullReference(image)
+	for _, pattern := range config.Ignore {
+		re, err := compileGlob(pattern)
+		if err == nil && (re.MatchString(reference) || re.MatchString(image)) {
+			return true
+		}
+	}
+	return false
+}
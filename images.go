@@ -0,0 +1,86 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Rows of the reports: a row per container, or per image with the containers using it
+var reportMode string
+
+// An image and the containers running it, a row of -report images
+type ImageUsage struct {
+	Image        string     `json:"image"`
+	IsLatest     string     `json:"is_latest"` // the most outdated status of its containers
+	CurrentTags  string     `json:"current_tags,omitempty"`
+	LatestTags   string     `json:"latest_tags,omitempty"`
+	Severity     string     `json:"severity,omitempty"`
+	ChangelogURL string     `json:"changelog_url,omitempty"`
+	LatestPushed *time.Time `json:"latest_pushed,omitempty"`
+	Containers   []string   `json:"containers"` // container@host for other hosts than the default daemon
+}
+
+// Renderers of the formats with a row per image, the other formats keep a row per container
+var imageFormats = map[string]func([]CheckResult) ([]byte, error){
+	"json":     renderImagesJSON,
+	"markdown": renderImagesMarkdown,
+}
+
+// Group results by image, the most outdated images first, so updates can be planned image by image
+func imageUsage(results []CheckResult) []ImageUsage {
+	var images []ImageUsage
+	index := make(map[string]int)
+	for _, result := range results {
+		name := strings.TrimPrefix(result.Container, "/")
+		if result.Host != "" {
+			name += "@" + result.Host
+		}
+		i, ok := index[result.Image]
+		if !ok {
+			i = len(images)
+			index[result.Image] = i
+			images = append(images, ImageUsage{Image: result.Image, IsLatest: result.IsLatest})
+		}
+		image := &images[i]
+		// the row shows the tags of its most outdated container
+		if !ok || statusRank(result.IsLatest) < statusRank(image.IsLatest) {
+			image.IsLatest, image.CurrentTags, image.LatestTags = result.IsLatest, result.CurrentTags, result.LatestTags
+			image.Severity, image.ChangelogURL, image.LatestPushed = result.Severity, result.ChangelogURL, result.LatestPushed
+		}
+		image.Containers = append(image.Containers, name)
+	}
+	slices.SortStableFunc(images, func(a, b ImageUsage) int {
+		return cmp.Or(cmp.Compare(statusRank(a.IsLatest), statusRank(b.IsLatest)), strings.Compare(a.Image, b.Image))
+	})
+	return images
+}
+
+// Render a row per image as indented JSON
+func renderImagesJSON(results []CheckResult) ([]byte, error) {
+	data, err := json.MarshalIndent(imageUsage(results), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal json: %s", err)
+	}
+	return data, nil
+}
+
+// Render a row per image as a Markdown table
+func renderImagesMarkdown(results []CheckResult) ([]byte, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n\n", trf("Checked at %s, run %s", timestamp().Format(time.RFC3339), runID))
+	if summary := errorSummary(results); summary != "" {
+		fmt.Fprintf(&b, "**%s**: %s\n\n", tr("Partial results"), summary)
+	}
+	fmt.Fprintf(&b, "| %s | %s | %s | %s | %s |\n", markdownCell(tr("Image")), markdownCell(tr("Latest")), markdownCell(tr("Current tags")),
+		markdownCell(tr("Latest tags")), markdownCell(tr("Containers")))
+	b.WriteString("| --- | --- | --- | --- | --- |\n")
+	for _, image := range imageUsage(results) {
+		fmt.Fprintf(&b, "| `%s` | %s | %s | %s | %s |\n", markdownCell(image.Image), markdownCell(tr(image.IsLatest)), markdownCell(image.CurrentTags),
+			markdownCell(image.LatestTags), markdownCell(strings.Join(image.Containers, ", ")))
+	}
+	return []byte(b.String()), nil
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ref: https://developer.pagerduty.com/docs/events-api-v2/overview/
+type PagerDutyConfig struct {
+	RoutingKey string `json:"routing_key"` // integration key of an Events API v2 integration
+	Severity   string `json:"severity"`    // critical, error, warning or info, defaults to warning
+}
+
+// ref: https://docs.opsgenie.com/docs/alert-api
+type OpsgenieConfig struct {
+	APIKey   string `json:"api_key"`
+	URL      string `json:"url"`      // defaults to https://api.opsgenie.com, https://api.eu.opsgenie.com in the EU
+	Priority string `json:"priority"` // P1 to P5, defaults to P3
+}
+
+// A notifier opening an incident per outdated container, resolved once the container is up-to-date again
+type IncidentNotifier interface {
+	Notifier
+	Resolve(key string) error
+}
+
+func (c *PagerDutyConfig) Name() string { return "pagerduty" }
+func (c *OpsgenieConfig) Name() string  { return "opsgenie" }
+
+// Key of the incident of a result, by host, container and image
+func incidentKey(result CheckResult, host string) string {
+	if result.Host != "" {
+		host = result.Host
+	}
+	return host + "/" + strings.TrimPrefix(result.Container, "/") + "/" + result.Image
+}
+
+// Open an incident per outdated result, the notification of a still open one updates it
+func openIncidents(n IncidentNotifier, data NotificationData, trigger func(key string, result CheckResult) error) error {
+	for _, result := range data.Outdated {
+		key := incidentKey(result, data.Host)
+		err := trigger(key, result)
+		if err != nil {
+			return err
+		}
+		if _, ok := state.Incidents[n.Name()+" "+key]; !ok {
+			state.Incidents[n.Name()+" "+key] = time.Now()
+		}
+	}
+	if statePath == "" {
+		return nil
+	}
+	return SaveState(statePath, state)
+}
+
+// Resolve the open incidents without an outdated result left: the container is up-to-date again, acknowledged,
+// or, when full is set as every container was checked, it is gone or runs another image.
+// Failed checks don't tell, their incidents stay open.
+func resolveIncidents(results []CheckResult, host string, full bool) {
+	byKey := make(map[string]CheckResult)
+	for _, result := range results {
+		byKey[incidentKey(result, host)] = result
+	}
+	// the containers of an unreachable endpoint are missing from the results, but not gone
+	full = full && len(lastEndpointErrors()) == 0
+
+	changed := false
+	for _, n := range notifiers() {
+		n, ok := n.(IncidentNotifier)
+		if !ok {
+			continue
+		}
+		prefix := n.Name() + " "
+		for _, incident := range sortedKeys(state.Incidents) {
+			key, ok := strings.CutPrefix(incident, prefix)
+			if !ok {
+				continue
+			}
+			result, checked := byKey[key]
+			if checked && (slices.Contains(statusGroups["outdated"], result.IsLatest) || slices.Contains(statusGroups["unknown"], result.IsLatest)) {
+				continue
+			}
+			if !checked && !full {
+				continue
+			}
+			if err := n.Resolve(key); err != nil {
+				log.Println("Unable to resolve "+n.Name()+" incident:", key, err)
+				continue
+			}
+			delete(state.Incidents, incident)
+			changed = true
+		}
+	}
+	if changed && statePath != "" {
+		if err := SaveState(statePath, state); err != nil {
+			log.Println("Unable to save state:", err)
+		}
+	}
+}
+
+// Post body as JSON to url
+func postJSON(url string, body any, header http.Header) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("error while marshalling request: %s", err)
+	}
+	req, err := http.NewRequest("POST", url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("error while creating request: %s", err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return sendNotification(req)
+}
+
+// ref: https://developer.pagerduty.com/api-reference/368ae3d938c9e-send-an-event-to-pager-duty
+func (c *PagerDutyConfig) event(action string, key string, payload map[string]any) error {
+	event := map[string]any{
+		"routing_key":  c.RoutingKey,
+		"event_action": action,
+		"dedup_key":    key,
+	}
+	if payload != nil {
+		event["payload"] = payload
+	}
+	return postJSON("https://events.pagerduty.com/v2/enqueue", event, nil)
+}
+
+func (c *PagerDutyConfig) Notify(_ string, _ string, data NotificationData) error {
+	severity := c.Severity
+	if severity == "" {
+		severity = "warning"
+	}
+	return openIncidents(c, data, func(key string, result CheckResult) error {
+		return c.event("trigger", key, map[string]any{
+			"summary":  fmt.Sprintf("%s runs outdated %s", strings.TrimPrefix(result.Container, "/"), result.Image),
+			"source":   strings.SplitN(key, "/", 2)[0],
+			"severity": severity,
+			"custom_details": map[string]string{
+				"current_digest": result.CurrentDigest,
+				"latest_digest":  result.LatestDigest,
+				"latest_tags":    result.LatestTags,
+				"severity":       result.Severity,
+				"changelog":      result.ChangelogURL,
+			},
+		})
+	})
+}
+
+func (c *PagerDutyConfig) Resolve(key string) error {
+	return c.event("resolve", key, nil)
+}
+
+func (c *OpsgenieConfig) api() (string, http.Header) {
+	server := c.URL
+	if server == "" {
+		server = "https://api.opsgenie.com"
+	}
+	return strings.TrimSuffix(server, "/") + "/v2/alerts", http.Header{"Authorization": {"GenieKey " + c.APIKey}}
+}
+
+// Opsgenie aliases are limited to 512 characters
+func opsgenieAlias(key string) string {
+	if len(key) > 512 {
+		return key[:512]
+	}
+	return key
+}
+
+func (c *OpsgenieConfig) Notify(_ string, _ string, data NotificationData) error {
+	priority := c.Priority
+	if priority == "" {
+		priority = "P3"
+	}
+	alerts, header := c.api()
+	return openIncidents(c, data, func(key string, result CheckResult) error {
+		return postJSON(alerts, map[string]any{
+			"message":  fmt.Sprintf("%s runs outdated %s", strings.TrimPrefix(result.Container, "/"), result.Image),
+			"alias":    opsgenieAlias(key),
+			"source":   commandName,
+			"priority": priority,
+			"details": map[string]string{
+				"host":           strings.SplitN(key, "/", 2)[0],
+				"current_digest": result.CurrentDigest,
+				"latest_digest":  result.LatestDigest,
+				"latest_tags":    result.LatestTags,
+				"severity":       result.Severity,
+				"changelog":      result.ChangelogURL,
+			},
+		}, header)
+	})
+}
+
+// ref: https://docs.opsgenie.com/docs/alert-api#close-alert
+func (c *OpsgenieConfig) Resolve(key string) error {
+	alerts, header := c.api()
+	return postJSON(alerts+"/"+url.PathEscape(opsgenieAlias(key))+"/close?identifierType=alias", map[string]string{"source": commandName}, header)
+}
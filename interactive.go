@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Ask before updating each outdated container
+var interactiveUpdate bool
+
+// Answer given for the remaining containers, all or skip, empty while asking about each
+var interactiveAnswer string
+
+var interactiveInput = bufio.NewReader(os.Stdin)
+
+// Ask on the terminal whether to update the container of result:
+// y updates it, n leaves it, all updates it and the remaining ones, skip leaves the remaining ones
+func confirmUpdate(result CheckResult) bool {
+	switch interactiveAnswer {
+	case "all":
+		return true
+	case "skip":
+		return false
+	}
+
+	target := result.LatestTags
+	if target == "" {
+		target = "latest digest"
+	}
+	for {
+		fmt.Fprintf(os.Stderr, "Update %s (%s -> %s)? [y/n/all/skip] ", strings.TrimPrefix(result.Container, "/"), result.Image, target)
+		line, err := interactiveInput.ReadString('\n')
+		answer := strings.ToLower(strings.TrimSpace(line))
+		// no more answers, e.g. stdin was closed
+		if err != nil && answer == "" {
+			fmt.Fprintln(os.Stderr)
+			interactiveAnswer = "skip"
+			return false
+		}
+		switch answer {
+		case "y", "yes":
+			return true
+		case "n", "no":
+			return false
+		case "a", "all":
+			interactiveAnswer = "all"
+			return true
+		case "s", "skip":
+			interactiveAnswer = "skip"
+			return false
+		}
+	}
+}
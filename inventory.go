@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Inventory file of the hosts and images to check instead of the containers of a daemon
+var inventoryPath string
+
+// Hosts and images to check, e.g. exported from a CMDB, in YAML or JSON
+type Inventory struct {
+	Hosts []InventoryHost `yaml:"hosts"`
+}
+
+type InventoryHost struct {
+	Name       string               `yaml:"name"`
+	Containers []InventoryContainer `yaml:"containers"`
+}
+
+type InventoryContainer struct {
+	Name   string            `yaml:"name"`   // the image when empty
+	Image  string            `yaml:"image"`  // e.g. nginx:1.25, or nginx:1.25@sha256:... for the digest it runs
+	Labels map[string]string `yaml:"labels"` // e.g. is-latest.compare_tag or com.docker.compose.project
+}
+
+// Read the inventory file at path
+func LoadInventory(path string) (Inventory, error) {
+	var inventory Inventory
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return inventory, fmt.Errorf("error while reading inventory: %s", err)
+	}
+	err = yaml.Unmarshal(data, &inventory)
+	if err != nil {
+		return inventory, fmt.Errorf("error while parsing inventory: %s", err)
+	}
+	for _, host := range inventory.Hosts {
+		for i, c := range host.Containers {
+			if c.Image == "" {
+				return inventory, fmt.Errorf("error while parsing inventory: container %d of host %q has no image", i+1, host.Name)
+			}
+		}
+	}
+	return inventory, nil
+}
+
+// Containers standing in for the images of the inventory, the results of each host are reported under its name
+func containersFromInventory() ([]Container, error) {
+	inventory, err := LoadInventory(inventoryPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var containers []Container
+	for _, host := range inventory.Hosts {
+		for _, ic := range host.Containers {
+			name := ic.Name
+			if name == "" {
+				name = ic.Image
+			}
+			c := referenceContainer(name, ic.Image)
+			c.Endpoint = DockerEndpoint{Name: host.Name}
+			c.Labels = ic.Labels
+			containers = append(containers, c)
+		}
+	}
+	return containers, nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"time"
+)
+
+// Longest check of a container, from its registry lookups to its enrichment, 0 for unlimited
+var checkTimeout time.Duration
+
+const defaultCheckTimeout = 5 * time.Minute
+
+// Time a timed out check has to return once its requests are cancelled, before it is left behind
+const checkStopGrace = 10 * time.Second
+
+var (
+	errCheckTimeout = errors.New("check timed out")
+	errCheckPanic   = errors.New("check panicked")
+)
+
+// Run fn on a copy of result, isolated from the run: its context ends at deadline, which cancels its requests,
+// and a panic or going past the deadline only fails the check of container. The result is returned unchanged with the failure.
+func isolated(ctx context.Context, container Container, deadline time.Time, result CheckResult, fn func(context.Context, *CheckResult)) (CheckResult, error) {
+	if !deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
+
+	done := make(chan CheckResult, 1)
+	failed := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Println("Check of", container.Names[0], "panicked:", r, "\n"+string(debug.Stack()))
+				failed <- fmt.Errorf("%w: %v", errCheckPanic, r)
+			}
+		}()
+		r := result
+		fn(ctx, &r)
+		done <- r
+	}()
+
+	select {
+	case r := <-done:
+		return r, nil
+	case err := <-failed:
+		return result, err
+	case <-ctx.Done():
+	}
+	log.Println("Check of", container.Names[0], "timed out after", checkTimeout)
+	// the cancelled requests make fn return, it must not touch the state of the next checks
+	select {
+	case <-done:
+	case <-failed:
+	case <-time.After(checkStopGrace):
+		log.Println("Check of", container.Names[0], "didn't stop within", checkStopGrace, "after its timeout")
+	}
+	return result, fmt.Errorf("%w after %s", errCheckTimeout, checkTimeout)
+}
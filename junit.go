@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// JUnit XML report, as read by Jenkins and GitLab
+// ref: https://github.com/testmoapp/junitxml
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure"`
+	Error     *junitMessage `xml:"error"`
+	Skipped   *junitMessage `xml:"skipped"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// Render results as a JUnit XML report, one test suite per host with a test case per container failing when it is outdated
+func renderJUnit(results []CheckResult) ([]byte, error) {
+	report := junitTestSuites{Name: commandName}
+	suites := make(map[string]*junitTestSuite)
+	var hosts []string
+	for _, result := range results {
+		host := result.Host
+		if host == "" {
+			host = "docker"
+		}
+		suite, ok := suites[host]
+		if !ok {
+			suite = &junitTestSuite{Name: host, Timestamp: timestamp().Format(time.RFC3339)}
+			suites[host] = suite
+			hosts = append(hosts, host)
+		}
+
+		testCase := junitTestCase{Name: strings.TrimPrefix(result.Container, "/"), ClassName: host}
+		if result.Timings != nil {
+			testCase.Time = float64(result.Timings.Total) / 1000
+		}
+		switch result.IsLatest {
+		case "yes", "acknowledged", "too-new":
+		case "no", "base-outdated":
+			message := result.Image + " is outdated"
+			if result.IsLatest == "base-outdated" {
+				message = "the base image " + result.BaseImage + " of " + result.Image + " is outdated"
+			}
+			text := message + ", latest tags: " + result.LatestTags
+			if result.ChangelogURL != "" {
+				text += "\nRelease notes: " + result.ChangelogURL
+			}
+			testCase.Failure = &junitMessage{Message: message, Type: result.Severity, Text: text}
+			suite.Failures++
+		case "superseded-locally":
+			testCase.Failure = &junitMessage{Message: result.Image + " is superseded locally", Text: result.Warning}
+			suite.Failures++
+		case "repo-moved":
+			testCase.Failure = &junitMessage{Message: "the repository of " + result.Image + " moved", Text: result.Warning}
+			suite.Failures++
+		case "ignored", "untagged":
+			testCase.Skipped = &junitMessage{Message: result.IsLatest}
+			suite.Skipped++
+		default:
+			message := result.Error
+			if message == "" {
+				message = result.IsLatest
+			}
+			testCase.Error = &junitMessage{Message: message, Type: result.ErrorCode}
+			suite.Errors++
+		}
+		suite.Tests++
+		suite.Cases = append(suite.Cases, testCase)
+	}
+
+	for _, host := range hosts {
+		suite := suites[host]
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Errors += suite.Errors
+		report.Skipped += suite.Skipped
+		report.Suites = append(report.Suites, *suite)
+	}
+	data, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal junit: %s", err)
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
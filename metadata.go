@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// Registry responses recorded by export-metadata, replayed instead of network requests when set
+var (
+	metadataPath string
+	metadata     map[string]HTTPResponse
+)
+
+// Read the registry responses recorded by export-metadata
+func LoadMetadata(path string) (map[string]HTTPResponse, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error while reading metadata: %s", err)
+	}
+
+	var m map[string]HTTPResponse
+	err = json.Unmarshal(data, &m)
+	if err != nil {
+		return nil, fmt.Errorf("error while unmarshalling metadata: %s", err)
+	}
+	return m, nil
+}
+
+// Read image references from a results JSON file (e.g. the output of the air-gapped host) or a file with one per line
+func readImageList(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error while reading image list: %s", err)
+	}
+
+	var results []CheckResult
+	if json.Unmarshal(data, &results) == nil {
+		var images []string
+		for _, result := range results {
+			images = append(images, result.Image)
+		}
+		return images, nil
+	}
+
+	var images []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			images = append(images, line)
+		}
+	}
+	return images, nil
+}
+
+// Fetch everything checking a container of image needs: the compared and current tags, and the tag list
+func prefetchMetadata(reference string) {
+	imageName, imageTag := parseReference(reference)
+
+	_, err := GetRemoteDockerInfo(context.Background(), imageName, compareTag(Container{}, imageName), nil)
+	if err != nil {
+		log.Println("Unable to get remote docker tag:", reference, err)
+	}
+	_, err = GetRemoteDockerInfo(context.Background(), imageName, imageTag, nil)
+	if err != nil {
+		log.Println("Unable to get remote docker tag:", reference, err)
+	}
+	// the tag pages also resolve local digests to tags and versions without latest
+	_, err = remoteTagNames(context.Background(), imageName)
+	if err != nil {
+		log.Println("Unable to list remote docker tags:", reference, err)
+	}
+}
+
+// export-metadata [-o metadata.json] [-i images] [image:tag...]: record the registry responses for the images,
+// to check them with -metadata-file on a host without internet access
+func runExportMetadata(args []string) {
+	fs := flag.NewFlagSet("export-metadata", flag.ExitOnError)
+	outPath := fs.String("o", "metadata.json", "Metadata file path")
+	imagesPath := fs.String("i", "", "Results JSON file or list of images, one per line")
+	fs.Parse(args)
+
+	images := fs.Args()
+	if *imagesPath != "" {
+		list, err := readImageList(*imagesPath)
+		if err != nil {
+			log.Fatal("Unable to read image list:", err)
+		}
+		images = append(images, list...)
+	}
+	if len(images) == 0 {
+		log.Fatal("No images given, pass image:tag arguments or -i")
+	}
+
+	resetCache()
+	for _, image := range images {
+		prefetchMetadata(image)
+	}
+
+	data, err := json.Marshal(cache.HTTPCache)
+	if err != nil {
+		log.Fatal("Unable to marshal json:", err)
+	}
+	err = os.WriteFile(*outPath, data, 0o644)
+	if err != nil {
+		log.Fatal("Unable to write file:", err)
+	}
+	log.Printf("Recorded %d responses for %d images to %s", len(cache.HTTPCache), len(images), *outPath)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// A registry API serving the images of a registry: the registry itself or one of its mirrors
+type registryEndpoint struct {
+	host   string // host of the API, also the key of its config and credentials
+	prefix string // path of the mirrored registry on a mirror, e.g. docker.io of m.daocloud.io/docker.io
+}
+
+// Endpoints of a registry in the order they are tried, given by the mirrors of its config,
+// the registry itself last when they don't list it
+func registryEndpoints(registry string) []registryEndpoint {
+	self := registryEndpoint{host: registry}
+	var endpoints []registryEndpoint
+	listed := false
+	for _, mirror := range registryConfig(registry).Mirrors {
+		host, prefix, _ := strings.Cut(strings.Trim(mirror, "/"), "/")
+		if host == registry && prefix == "" {
+			listed = true
+		}
+		endpoints = append(endpoints, registryEndpoint{host: host, prefix: prefix})
+	}
+	if !listed {
+		endpoints = append(endpoints, self)
+	}
+	return endpoints
+}
+
+// Repository path of a repository of the registry on the endpoint
+func (e registryEndpoint) repository(repository string) string {
+	if e.prefix == "" {
+		return repository
+	}
+	return e.prefix + "/" + repository
+}
+
+// Check if lookups of registry fail over between mirrors
+func hasMirrors(registry string) bool {
+	return len(registryConfig(registry).Mirrors) > 0
+}
+
+// Why a response calls for the next endpoint: the request failed, or the registry is rate limiting or failing.
+// nil for any other answer, including a 404 every endpoint would give.
+func unavailable(resp HTTPResponse, err error) error {
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Log a failover to the next endpoint of a registry
+func logFailover(from registryEndpoint, err error) {
+	if verbose {
+		log.Println("Failing over from", from.host+"/"+from.prefix, "to the next endpoint:", err)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+)
+
+// Repository of a moved-to phrase, ns/name or the name of an official image
+const hubRepositoryPattern = `[a-z0-9](?:[a-z0-9._-]*[a-z0-9])?(?:/[a-z0-9](?:[a-z0-9._-]*[a-z0-9])?)?`
+
+var (
+	// Leading deprecation marker of a Docker Hub description, e.g. "DEPRECATED: ..." or "# Deprecated"
+	hubDeprecatedRegexp = regexp.MustCompile(`(?i)^[\s#*_>]*deprecated\b`)
+	// Moved-to phrase naming the new repository in backticks, as a link, as a Hub URL or as ns/name, e.g.
+	// "moved to vendor/app" or "This image is deprecated in favor of the [`eclipse-temurin`](...) image"
+	hubMovedToRegexp = regexp.MustCompile(`(?i)\b(?:moved to|migrated to|in favou?r of|replaced by|superseded by)\s+(?:the\s+)?` +
+		"(?:\\[?`(" + hubRepositoryPattern + ")`|\\[(" + hubRepositoryPattern + `)\]|https?://hub\.docker\.com/(?:_|r)/(` + hubRepositoryPattern + `)|([a-z0-9][a-z0-9._-]*/` + hubRepositoryPattern + "))")
+)
+
+// Repository info of a docker.io repository
+// doc: https://docs.docker.com/docker-hub/api/latest/#tag/repositories
+type DockerHubRepository struct {
+	Description     string `json:"description"`
+	FullDescription string `json:"full_description"`
+}
+
+// Where a docker.io repository moved to according to its deprecation notice, moved is false when it has none.
+// The location is empty when the notice doesn't name the new repository.
+func dockerHubMovedTo(ctx context.Context, image string) (location string, moved bool, err error) {
+	_, namespace, name := parseImage(image)
+	resp, err := dockerHubFetch(ctx, image, fmt.Sprintf("https://registry.hub.docker.com/v2/repositories/%s/%s/", namespace, name))
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return "", false, err
+	}
+	var repository DockerHubRepository
+	err = json.Unmarshal(resp.Body, &repository)
+	if err != nil {
+		return "", false, fmt.Errorf("server error while unmarshalling body: %s", err)
+	}
+
+	// the notice comes first, the rest of a long description is about the image
+	notice := repository.Description + "\n" + repository.FullDescription[:min(len(repository.FullDescription), 2000)]
+	deprecated := hubDeprecatedRegexp.MatchString(repository.Description) || hubDeprecatedRegexp.MatchString(repository.FullDescription)
+	m := hubMovedToRegexp.FindStringSubmatch(notice)
+	if !deprecated && m == nil {
+		return "", false, nil
+	}
+	for _, group := range m[min(len(m), 1):] {
+		if group != "" {
+			location = group
+			break
+		}
+	}
+	return location, true, nil
+}
+
+// Report the repository of result as moved to location, configured by repository_aliases or found on Docker Hub
+func repoMoved(result CheckResult, location string) CheckResult {
+	explain("moved to", "%s", location)
+	result.IsLatest = "repo-moved"
+	result.MovedTo = location
+	result.Warning = "the repository is deprecated"
+	if location != "" {
+		result.Warning = "the repository moved to " + location
+	}
+	result.Error, result.ErrorCode = "", ""
+	return result
+}
+
+// Check whether a docker.io repository whose tag wasn't found moved, the result is unchanged when it didn't
+func checkDockerHubMoved(ctx context.Context, imageName string, result CheckResult) (CheckResult, bool) {
+	location, moved, err := dockerHubMovedTo(ctx, imageName)
+	if err != nil || !moved {
+		return result, false
+	}
+	return repoMoved(result, location), true
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Nagios plugin exit codes
+// ref: https://nagios-plugins.org/doc/guidelines.html#AEN78
+const (
+	nagiosOK       = 0
+	nagiosWarning  = 1
+	nagiosCritical = 2
+	nagiosUnknown  = 3
+)
+
+var nagiosStatusNames = []string{"OK", "WARNING", "CRITICAL", "UNKNOWN"}
+
+// Thresholds on the number of outdated containers, 0 disables a threshold
+var (
+	nagiosWarningThreshold  int
+	nagiosCriticalThreshold int
+)
+
+// Count the results by status
+func countResults(results []CheckResult) (outdated []string, upToDate int, unknown int) {
+	for _, result := range results {
+		switch result.IsLatest {
+		case "no":
+			outdated = append(outdated, result.Container)
+		case "yes", "acknowledged", "too-new":
+			upToDate++
+		default:
+			unknown++
+		}
+	}
+	return outdated, upToDate, unknown
+}
+
+// Nagios status of the results, by the number of outdated containers
+func nagiosStatus(results []CheckResult) int {
+	outdated, _, _ := countResults(results)
+	switch {
+	case nagiosCriticalThreshold > 0 && len(outdated) >= nagiosCriticalThreshold:
+		return nagiosCritical
+	case nagiosWarningThreshold > 0 && len(outdated) >= nagiosWarningThreshold:
+		return nagiosWarning
+	}
+	return nagiosOK
+}
+
+// Render results as a Nagios plugin status line with perfdata, followed by one line per outdated container
+func renderNagios(results []CheckResult) ([]byte, error) {
+	outdated, upToDate, unknown := countResults(results)
+	status := nagiosStatus(results)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "IS-LATEST %s - %d of %d containers outdated", nagiosStatusNames[status], len(outdated), len(results))
+	if len(outdated) > 0 {
+		b.WriteString(": " + strings.Join(outdated, ", "))
+	}
+	// perfdata: 'label'=value;warn;crit;min;max
+	threshold := func(n int) string {
+		if n <= 0 {
+			return ""
+		}
+		return fmt.Sprint(n)
+	}
+	fmt.Fprintf(&b, " | outdated=%d;%s;%s;0;%d up_to_date=%d;;;0;%d unknown=%d;;;0;%d\n",
+		len(outdated), threshold(nagiosWarningThreshold), threshold(nagiosCriticalThreshold), len(results),
+		upToDate, len(results), unknown, len(results))
+
+	for _, result := range results {
+		if result.IsLatest == "no" {
+			fmt.Fprintf(&b, "%s %s {%s} => {%s}\n", result.Container, result.Image, result.CurrentTags, result.LatestTags)
+		}
+	}
+	return []byte(b.String()), nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+)
+
+// Outputs streaming the results of the running run as ndjson, by path
+var ndjsonStreams = make(map[string]io.Writer)
+
+// Render results as one JSON object per line
+func renderNDJSON(results []CheckResult) ([]byte, error) {
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+	for _, result := range results {
+		err := encoder.Encode(result)
+		if err != nil {
+			return nil, fmt.Errorf("unable to marshal json: %s", err)
+		}
+	}
+	return b.Bytes(), nil
+}
+
+// Open the local ndjson outputs, so results are written as soon as they are known instead of at the end of the run
+func openNDJSONStreams() {
+	for _, path := range outputPathsOrStdout() {
+		if isRemoteOutput(path) || formatOfOutput(path) != "ndjson" {
+			continue
+		}
+		if path == "-" {
+			ndjsonStreams[path] = os.Stdout
+			continue
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			// writeOutput tries again at the end of the run
+			log.Println("Unable to open output:", err)
+			continue
+		}
+		ndjsonStreams[path] = f
+	}
+}
+
+// Write a result to the ndjson outputs
+func streamResult(result CheckResult) {
+	if len(ndjsonStreams) == 0 {
+		return
+	}
+	line, err := json.Marshal(result)
+	if err != nil {
+		log.Println("Unable to marshal json:", err)
+		return
+	}
+	line = append(line, '\n')
+	for path, w := range ndjsonStreams {
+		_, err = w.Write(line)
+		if err != nil {
+			log.Println("Unable to write output", path+":", err)
+		}
+	}
+}
+
+func closeNDJSONStreams() {
+	for path, w := range ndjsonStreams {
+		if f, ok := w.(*os.File); ok && f != os.Stdout {
+			err := f.Close()
+			if err != nil {
+				log.Println("Unable to write output", path+":", err)
+			}
+		}
+		delete(ndjsonStreams, path)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How long a missing tag or an unsupported registry is remembered across runs, 0 to look it up every run
+var failureCacheTTL time.Duration
+
+// A lookup failure remembered until expires
+type cachedFailure struct {
+	err     error
+	expires time.Time
+}
+
+var (
+	failureCache   = make(map[string]cachedFailure) // by image:tag
+	failureCacheMu sync.Mutex                       // pushes forget failures outside of the runs
+)
+
+// Error of a failure served from the failure cache, already logged when it was cached
+type cachedError struct {
+	err error
+}
+
+func (e cachedError) Error() string { return e.err.Error() }
+func (e cachedError) Unwrap() error { return e.err }
+
+// Check if err was served from the failure cache
+func isCachedFailure(err error) bool {
+	var cached cachedError
+	return errors.As(err, &cached)
+}
+
+// Failure of a lookup of image:tag still cached, nil when there is none
+func lookupFailure(image string, tag string) error {
+	failureCacheMu.Lock()
+	defer failureCacheMu.Unlock()
+	f, ok := failureCache[image+":"+tag]
+	if !ok {
+		return nil
+	}
+	if time.Now().After(f.expires) {
+		delete(failureCache, image+":"+tag)
+		return nil
+	}
+	return cachedError{f.err}
+}
+
+// Remember a failure that won't go away by asking again soon, a missing tag or an unsupported registry
+func rememberFailure(image string, tag string, err error) {
+	if failureCacheTTL <= 0 || !(errors.Is(err, errNotFound) || errors.Is(err, errUnsupportedRegistry)) {
+		return
+	}
+	failureCacheMu.Lock()
+	defer failureCacheMu.Unlock()
+	failureCache[image+":"+tag] = cachedFailure{err, time.Now().Add(failureCacheTTL)}
+}
+
+// Forget the failures of the tags of repository, e.g. when a push was announced for it
+func forgetFailures(repository string) {
+	failureCacheMu.Lock()
+	defer failureCacheMu.Unlock()
+	for key := range failureCache {
+		if strings.HasPrefix(key, repository+":") {
+			delete(failureCache, key)
+		}
+	}
+}
@@ -0,0 +1,441 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+)
+
+type NtfyConfig struct {
+	URL      string `json:"url"` // defaults to https://ntfy.sh
+	Topic    string `json:"topic"`
+	Token    string `json:"token"`
+	Priority string `json:"priority"`
+}
+
+type GotifyConfig struct {
+	URL      string `json:"url"`
+	Token    string `json:"token"` // application token
+	Priority int    `json:"priority"`
+}
+
+type PushoverConfig struct {
+	Token string `json:"token"` // application API token
+	User  string `json:"user"`  // user or group key
+}
+
+// Apprise notification URLs, sent through an Apprise API server when URL is set, otherwise by the apprise CLI
+// ref: https://github.com/caronc/apprise/wiki/URLBasics
+type AppriseConfig struct {
+	URL  string   `json:"url"` // e.g. http://apprise:8000/notify or http://apprise:8000/notify/{key}
+	URLs []string `json:"urls"`
+	Tag  string   `json:"tag"`
+}
+
+type NotifyConfig struct {
+	Ntfy     *NtfyConfig     `json:"ntfy"`
+	Gotify   *GotifyConfig   `json:"gotify"`
+	Pushover *PushoverConfig `json:"pushover"`
+	Apprise  *AppriseConfig  `json:"apprise"`
+
+	PagerDuty *PagerDutyConfig `json:"pagerduty"`
+	Opsgenie  *OpsgenieConfig  `json:"opsgenie"`
+
+	// Go templates executed with NotificationData
+	TitleTemplate   string `json:"title_template"`
+	MessageTemplate string `json:"message_template"`
+
+	// Only notify about results at least this far behind: digest, patch, minor or major,
+	// or with security fixes whatever their severity
+	MinSeverity string `json:"min_severity"`
+
+	// Don't notify again about the same update of an image within this duration, e.g. 24h
+	DedupeWindow string        `json:"dedupe_window"`
+	dedupeWindow time.Duration // DedupeWindow, parsed by validateConfig
+
+	// Notifiers by severity of the results they get, "unknown" without severity and "*" for the others,
+	// e.g. {"major": ["pagerduty"], "minor": ["ntfy"], "unknown": []}, all notifiers get the results of unrouted severities
+	Routes map[string][]string `json:"routes"`
+}
+
+// Check if result is routed to the notifier of that name
+func routed(result CheckResult, notifier string) bool {
+	severity := result.Severity
+	if severity == "" {
+		severity = "unknown"
+	}
+	names, ok := config.Notify.Routes[severity]
+	if !ok {
+		names, ok = config.Notify.Routes["*"]
+	}
+	return !ok || slices.Contains(names, notifier)
+}
+
+// A service notified about the outdated containers of a run
+type Notifier interface {
+	Name() string
+	Notify(title string, message string, data NotificationData) error
+}
+
+func (c *NtfyConfig) Name() string     { return "ntfy" }
+func (c *GotifyConfig) Name() string   { return "gotify" }
+func (c *PushoverConfig) Name() string { return "pushover" }
+func (c *AppriseConfig) Name() string  { return "apprise" }
+
+func (c *NtfyConfig) Notify(title string, message string, _ NotificationData) error {
+	return sendNtfy(c, title, message)
+}
+
+func (c *GotifyConfig) Notify(title string, message string, _ NotificationData) error {
+	return sendGotify(c, title, message)
+}
+
+func (c *PushoverConfig) Notify(title string, message string, _ NotificationData) error {
+	return sendPushover(c, title, message)
+}
+
+func (c *AppriseConfig) Notify(title string, message string, _ NotificationData) error {
+	return sendApprise(c, title, message)
+}
+
+// The configured notifiers, in the order they are notified
+func notifiers() []Notifier {
+	return configuredNotifiers(config.Notify)
+}
+
+// The notifiers of notify and -notify-exec, in the order they are notified
+func configuredNotifiers(notify NotifyConfig) []Notifier {
+	var list []Notifier
+	if c := notify.Ntfy; c != nil {
+		list = append(list, c)
+	}
+	if c := notify.Gotify; c != nil {
+		list = append(list, c)
+	}
+	if c := notify.Pushover; c != nil {
+		list = append(list, c)
+	}
+	if c := notify.Apprise; c != nil {
+		list = append(list, c)
+	}
+	if c := notify.PagerDuty; c != nil {
+		list = append(list, c)
+	}
+	if c := notify.Opsgenie; c != nil {
+		list = append(list, c)
+	}
+	for _, command := range notifyExec {
+		if strings.TrimSpace(command) != "" {
+			list = append(list, execNotifier(command))
+		}
+	}
+	return list
+}
+
+type NotificationData struct {
+	RunID    string
+	Host     string
+	Outdated []CheckResult
+	Images   []OutdatedImage // the outdated results grouped by image
+	Stacks   []OutdatedStack // the outdated images grouped by compose project or swarm stack
+	Results  []CheckResult
+}
+
+// An outdated image and the containers running it
+type OutdatedImage struct {
+	CheckResult
+	Containers []string
+	Dependents []string // containers depending on the containers running it, e.g. the apps of an outdated redis
+}
+
+// The outdated images of a compose project or swarm stack, the containers of neither have an empty name
+type OutdatedStack struct {
+	Name   string
+	Images []OutdatedImage
+}
+
+// Compose project or swarm stack of a container
+func stackName(c Container) string {
+	if project := c.Labels[labelComposeProject]; project != "" {
+		return project
+	}
+	return c.Labels[labelStackNamespace]
+}
+
+// Add an outdated result of container c to the images, with the containers depending on c
+func addOutdatedImage(images []OutdatedImage, result CheckResult, c Container, containers []Container) []OutdatedImage {
+	i := slices.IndexFunc(images, func(image OutdatedImage) bool {
+		return image.Image == result.Image && image.CurrentDigest == result.CurrentDigest
+	})
+	if i < 0 {
+		images = append(images, OutdatedImage{CheckResult: result})
+		i = len(images) - 1
+	}
+	images[i].Containers = append(images[i].Containers, result.Container)
+	images[i].Dependents = slices.DeleteFunc(images[i].Dependents, func(name string) bool { return name == result.Container })
+
+	for _, dependent := range containers {
+		if _, ok := dependsOn(dependent, c); !ok {
+			continue
+		}
+		if name := dependent.Names[0]; !slices.Contains(images[i].Dependents, name) && !slices.Contains(images[i].Containers, name) {
+			images[i].Dependents = append(images[i].Dependents, name)
+		}
+	}
+	return images
+}
+
+const (
+	defaultTitleTemplate   = `{{printf (tr "%d container(s) outdated on %s") (len .Outdated) .Host}}`
+	defaultMessageTemplate = `{{range .Stacks}}{{if .Name}}[{{.Name}}]
+{{end}}{{range .Images}}{{.Image}}{{if .LatestTags}} -> {{.LatestTags}}{{end}}{{if .SecurityFixes}} [{{tr "security"}}]{{end}}{{if eq (len .Containers) 1}} ({{.Container}}){{else}} ({{printf (tr "affects %d containers") (len .Containers)}}){{end}}{{if .Dependents}}, {{tr "dependents"}}: {{join .Dependents ", "}}{{end}}
+{{end}}{{end}}{{tr "run"}} {{.RunID}}`
+)
+
+// Send the request and treat non-2xx responses as errors
+func sendNotification(req *http.Request) error {
+	setRequestHeaders(req)
+	client := &http.Client{
+		Transport: transport,
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error while sending notification to %s: %s", req.URL.Host, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("error %s from %s: %s", resp.Status, req.URL.Host, string(body))
+	}
+	return nil
+}
+
+// ref: https://docs.ntfy.sh/publish/
+func sendNtfy(c *NtfyConfig, title string, message string) error {
+	server := c.URL
+	if server == "" {
+		server = "https://ntfy.sh"
+	}
+
+	req, err := http.NewRequest("POST", strings.TrimSuffix(server, "/")+"/"+c.Topic, strings.NewReader(message))
+	if err != nil {
+		return fmt.Errorf("error while creating request: %s", err)
+	}
+	req.Header.Set("Title", title)
+	if c.Priority != "" {
+		req.Header.Set("Priority", c.Priority)
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	return sendNotification(req)
+}
+
+// ref: https://gotify.net/api-docs#/message/createMessage
+func sendGotify(c *GotifyConfig, title string, message string) error {
+	body, err := json.Marshal(map[string]any{
+		"title":    title,
+		"message":  message,
+		"priority": c.Priority,
+	})
+	if err != nil {
+		return fmt.Errorf("error while marshalling gotify message: %s", err)
+	}
+
+	req, err := http.NewRequest("POST", strings.TrimSuffix(c.URL, "/")+"/message", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error while creating request: %s", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Gotify-Key", c.Token)
+	return sendNotification(req)
+}
+
+// ref: https://pushover.net/api
+func sendPushover(c *PushoverConfig, title string, message string) error {
+	form := url.Values{}
+	form.Set("token", c.Token)
+	form.Set("user", c.User)
+	form.Set("title", title)
+	form.Set("message", message)
+
+	req, err := http.NewRequest("POST", "https://api.pushover.net/1/messages.json", strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("error while creating request: %s", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return sendNotification(req)
+}
+
+// ref: https://github.com/caronc/apprise-api#api-details
+func sendApprise(c *AppriseConfig, title string, message string) error {
+	if c.URL == "" {
+		args := []string{"--title", title, "--body", message}
+		if c.Tag != "" {
+			args = append(args, "--tag", c.Tag)
+		}
+		out, err := exec.Command("apprise", append(args, c.URLs...)...).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("error while running apprise: %s %s", err, string(out))
+		}
+		return nil
+	}
+
+	payload := map[string]string{
+		"title": title,
+		"body":  message,
+	}
+	if len(c.URLs) > 0 {
+		payload["urls"] = strings.Join(c.URLs, ",")
+	}
+	if c.Tag != "" {
+		payload["tag"] = c.Tag
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("error while marshalling apprise message: %s", err)
+	}
+
+	req, err := http.NewRequest("POST", c.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error while creating request: %s", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return sendNotification(req)
+}
+
+// Render a notification template, falling back to the default one
+func renderNotification(name string, text string, fallback string, data NotificationData) (string, error) {
+	if text == "" {
+		text = fallback
+	}
+
+	tmpl, err := template.New(name).Funcs(template.FuncMap{"join": strings.Join, "tr": tr}).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("error while parsing %s: %s", name, err)
+	}
+
+	var b strings.Builder
+	err = tmpl.Execute(&b, data)
+	if err != nil {
+		return "", fmt.Errorf("error while executing %s: %s", name, err)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// Notification data of the outdated results, results[i] being the result of containers[i]
+func notificationData(containers []Container, results []CheckResult, outdated []int) NotificationData {
+	data := NotificationData{RunID: runID, Results: results}
+	data.Host, _ = os.Hostname()
+	for _, i := range outdated {
+		result := results[i]
+		data.Outdated = append(data.Outdated, result)
+		data.Images = addOutdatedImage(data.Images, result, containers[i], containers)
+
+		name := stackName(containers[i])
+		j := slices.IndexFunc(data.Stacks, func(stack OutdatedStack) bool { return stack.Name == name })
+		if j < 0 {
+			data.Stacks = append(data.Stacks, OutdatedStack{Name: name})
+			j = len(data.Stacks) - 1
+		}
+		data.Stacks[j].Images = addOutdatedImage(data.Stacks[j].Images, result, containers[i], containers)
+	}
+	return data
+}
+
+// Notify the configured push services about outdated containers, results[i] being the result of containers[i],
+// full when the run checked every container
+func Notify(containers []Container, results []CheckResult, full bool) {
+	host, _ := os.Hostname()
+	resolveIncidents(results, host, full)
+
+	window := config.Notify.dedupeWindow
+	now := time.Now()
+
+	var outdated []int
+	for i, result := range results {
+		if result.IsLatest != "no" || !reported(result) || (!severityAtLeast(result.Severity, config.Notify.MinSeverity) && len(result.SecurityFixes) == 0) {
+			continue
+		}
+		if notifyOn == "security" && len(result.SecurityFixes) == 0 {
+			continue
+		}
+		if notified, ok := state.Notified[notificationKey(result)]; ok && now.Sub(notified) < window {
+			continue
+		}
+		outdated = append(outdated, i)
+	}
+	if len(outdated) == 0 {
+		return
+	}
+	// only the updates a notifier accepted are kept from being notified again
+	var sent []int
+	if window > 0 {
+		defer func() {
+			if len(sent) > 0 {
+				recordNotified(notificationData(containers, results, sent).Outdated, now, window)
+			}
+		}()
+	}
+
+	// each notifier gets the results routed to it by severity
+	for _, n := range notifiers() {
+		routedOutdated := slices.DeleteFunc(slices.Clone(outdated), func(i int) bool { return !routed(results[i], n.Name()) })
+		if len(routedOutdated) == 0 {
+			continue
+		}
+		data := notificationData(containers, results, routedOutdated)
+
+		title, err := renderNotification("title_template", config.Notify.TitleTemplate, defaultTitleTemplate, data)
+		if err != nil {
+			log.Println("Unable to render notification for "+n.Name()+":", err)
+			continue
+		}
+		message, err := renderNotification("message_template", config.Notify.MessageTemplate, defaultMessageTemplate, data)
+		if err != nil {
+			log.Println("Unable to render notification for "+n.Name()+":", err)
+			continue
+		}
+		if err := n.Notify(title, message, data); err != nil {
+			log.Println("Unable to notify "+n.Name()+":", err)
+			continue
+		}
+		for _, i := range routedOutdated {
+			if !slices.Contains(sent, i) {
+				sent = append(sent, i)
+			}
+		}
+	}
+}
+
+// Key of the update of an outdated result: the image and its old and new digest
+func notificationKey(result CheckResult) string {
+	return result.Image + " " + result.CurrentDigest + "->" + result.LatestDigest
+}
+
+// Remember the notified updates in the state file, forgetting those older than the window
+func recordNotified(results []CheckResult, now time.Time, window time.Duration) {
+	for key, notified := range state.Notified {
+		if now.Sub(notified) >= window {
+			delete(state.Notified, key)
+		}
+	}
+	for _, result := range results {
+		state.Notified[notificationKey(result)] = now
+	}
+	err := SaveState(statePath, state)
+	if err != nil {
+		log.Println("Unable to save state:", err)
+	}
+}
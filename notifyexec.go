@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Programs notified with the notification as JSON on stdin
+var notifyExec stringList
+
+// How long a notifier program may run
+const notifyExecTimeout = 30 * time.Second
+
+// A program notified about the outdated containers, e.g. "./my-notifier --channel ops"
+type execNotifier string
+
+// JSON read by a notifier program on stdin
+type execNotification struct {
+	RunID    string        `json:"run_id"`
+	Host     string        `json:"host"`
+	Title    string        `json:"title"`
+	Message  string        `json:"message"`
+	Outdated []CheckResult `json:"outdated"`
+}
+
+func (command execNotifier) Name() string {
+	return filepath.Base(strings.Fields(string(command))[0])
+}
+
+// Run the program, a non-zero exit is an error with its output
+func (command execNotifier) Notify(title string, message string, data NotificationData) error {
+	input, err := json.Marshal(execNotification{RunID: data.RunID, Host: data.Host, Title: title, Message: message, Outdated: data.Outdated})
+	if err != nil {
+		return fmt.Errorf("error while marshalling notification: %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), notifyExecTimeout)
+	defer cancel()
+	args := strings.Fields(string(command))
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdin = bytes.NewReader(input)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("error while running %s: %s %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
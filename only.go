@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Statuses or groups of statuses reported by the logs, outputs and notifications, all when empty
+var onlyStatuses stringList
+
+// Groups of statuses -only accepts besides the statuses themselves
+var statusGroups = map[string][]string{
+	"outdated": {"no", "base-outdated", "tag-removed", "repo-moved", "superseded-locally"},
+	"unknown":  {"unknown", "error", "not-found", "registry-unavailable", "private-needs-auth", "warning", "skipped-budget"},
+	"uptodate": {"yes"},
+}
+
+// Check that every -only value is a status of statusOrder or a group of statusGroups
+func validateOnly() error {
+	for _, value := range onlyStatuses {
+		for _, only := range strings.Split(value, ",") {
+			only = strings.TrimSpace(only)
+			if _, ok := statusGroups[only]; !ok && !slices.Contains(statusOrder, only) {
+				return fmt.Errorf("unknown status %q, expecting one of %s or a status", only, strings.Join(sortedKeys(statusGroups), ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// Check if result is reported with -only
+func reported(result CheckResult) bool {
+	if len(onlyStatuses) == 0 {
+		return true
+	}
+	for _, value := range onlyStatuses {
+		for _, only := range strings.Split(value, ",") {
+			only = strings.TrimSpace(only)
+			if only == result.IsLatest || slices.Contains(statusGroups[only], result.IsLatest) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// The results reported with -only
+func reportedResults(results []CheckResult) []CheckResult {
+	return slices.DeleteFunc(slices.Clone(results), func(result CheckResult) bool { return !reported(result) })
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var (
+	outputPaths  stringList
+	outputFormat string
+)
+
+// Renderers by -format name
+var outputFormats = map[string]func([]CheckResult) ([]byte, error){
+	"json":          renderJSON,
+	"ndjson":        renderNDJSON,
+	"dot":           renderDOT,
+	"gha":           renderGHA,
+	"html":          renderHTML,
+	"junit":         renderJUnit,
+	"markdown":      renderMarkdown,
+	"nagios":        renderNagios,
+	"prom-textfile": renderPromTextfile,
+}
+
+// Render results as indented JSON
+func renderJSON(results []CheckResult) ([]byte, error) {
+	data, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal json: %s", err)
+	}
+	return data, nil
+}
+
+// Summary of what a run couldn't check, empty when every container was checked
+func errorSummary(results []CheckResult) string {
+	var failed []string
+	skipped := 0
+	for _, result := range results {
+		switch result.IsLatest {
+		case "error":
+			failed = append(failed, strings.TrimPrefix(result.Container, "/"))
+		case "skipped-budget":
+			skipped++
+		}
+	}
+
+	var parts []string
+	if len(failed) > 0 {
+		parts = append(parts, fmt.Sprintf("%d of %d containers could not be inspected (%s)", len(failed), len(results), strings.Join(failed, ", ")))
+	}
+	if skipped > 0 {
+		parts = append(parts, fmt.Sprintf("%d of %d containers skipped by the run budget", skipped, len(results)))
+	}
+	unreachable := lastEndpointErrors()
+	for _, name := range sortedKeys(unreachable) {
+		parts = append(parts, fmt.Sprintf("%s unreachable: %s", name, unreachable[name]))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Escape the pipes of a Markdown table cell, e.g. of the tags joined with |, the table would split the cell at them
+func markdownCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+// Render results as a Markdown table
+func renderMarkdown(results []CheckResult) ([]byte, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n\n", trf("Checked at %s, run %s", timestamp().Format(time.RFC3339), runID))
+	if summary := errorSummary(results); summary != "" {
+		fmt.Fprintf(&b, "**%s**: %s\n\n", tr("Partial results"), summary)
+	}
+
+	// results of other hosts than the default daemon get a section per host, in the order of the hosts
+	var hosts []string
+	byHost := make(map[string][]CheckResult)
+	for _, result := range results {
+		if _, ok := byHost[result.Host]; !ok {
+			hosts = append(hosts, result.Host)
+		}
+		byHost[result.Host] = append(byHost[result.Host], result)
+	}
+	for _, host := range hosts {
+		sections := len(hosts) > 1 || host != ""
+		if sections {
+			name := host
+			if name == "" {
+				name = "docker"
+			}
+			fmt.Fprintf(&b, "### %s\n\n", name)
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s |\n", markdownCell(tr("Container")), markdownCell(tr("Image")), markdownCell(tr("Latest")),
+			markdownCell(tr("Current tags")), markdownCell(tr("Latest tags")), markdownCell(tr("Changelog")))
+		b.WriteString("| --- | --- | --- | --- | --- | --- |\n")
+		for _, result := range byHost[host] {
+			changelog := ""
+			if result.ChangelogURL != "" {
+				changelog = "[" + tr("release notes") + "](" + result.ChangelogURL + ")"
+			}
+			container := strings.TrimPrefix(result.Container, "/")
+			if result.Collapsed > 1 {
+				container += " (" + trf("%d one-shot containers", result.Collapsed) + ")"
+			}
+			fmt.Fprintf(&b, "| %s | `%s` | %s | %s | %s | %s |\n", markdownCell(container), markdownCell(result.Image), markdownCell(tr(result.IsLatest)),
+				markdownCell(result.CurrentTags), markdownCell(result.LatestTags), markdownCell(changelog))
+		}
+		if sections {
+			b.WriteString("\n")
+		}
+	}
+	return []byte(b.String()), nil
+}
+
+// Content types of the formats, for uploaded outputs
+var outputContentTypes = map[string]string{
+	"json":          "application/json",
+	"ndjson":        "application/x-ndjson",
+	"dot":           "text/vnd.graphviz",
+	"gha":           "text/plain; charset=utf-8",
+	"html":          "text/html; charset=utf-8",
+	"junit":         "application/xml",
+	"markdown":      "text/markdown; charset=utf-8",
+	"nagios":        "text/plain; charset=utf-8",
+	"prom-textfile": "text/plain; version=0.0.4",
+}
+
+// The -output paths, stdout when only -format is given
+func outputPathsOrStdout() []string {
+	if len(outputPaths) == 0 && outputFormat != "" {
+		return []string{"-"}
+	}
+	return outputPaths
+}
+
+// The -format, or the format given by the extension of path
+func formatOfOutput(path string) string {
+	if outputFormat != "" {
+		return outputFormat
+	}
+	ext := filepath.Ext(path)
+	if isRemoteOutput(path) {
+		if u, err := url.Parse(path); err == nil {
+			ext = filepath.Ext(u.Path)
+		}
+	}
+	switch strings.ToLower(ext) {
+	case ".md":
+		return "markdown"
+	case ".html", ".htm":
+		return "html"
+	case ".xml":
+		return "junit"
+	case ".prom":
+		return "prom-textfile"
+	case ".ndjson", ".jsonl":
+		return "ndjson"
+	case ".dot", ".gv":
+		return "dot"
+	default:
+		return "json"
+	}
+}
+
+// Write results to every output path, in the -format or the format given by its extension
+func writeOutput(results []CheckResult) error {
+	results = collapseResults(reportedResults(results))
+	sortResults(results)
+	if scanner != "" {
+		sortByVulnerabilities(results)
+	}
+
+	// a failed upload doesn't keep the other outputs from being written
+	var uploadErrs []error
+	for _, path := range outputPathsOrStdout() {
+		// already written while the run was going
+		if _, ok := ndjsonStreams[path]; ok {
+			continue
+		}
+		remote := isRemoteOutput(path)
+		format := formatOfOutput(path)
+		render := outputFormats[format]
+		if r, ok := imageFormats[format]; ok && reportMode == "images" {
+			render = r
+		}
+
+		if info, err := os.Stat(path); !remote && format == "prom-textfile" && err == nil && info.IsDir() {
+			path = filepath.Join(path, promTextfileName)
+		}
+
+		data, err := render(results)
+		if err != nil {
+			return err
+		}
+		// stdout has nowhere to put the signature
+		var signature []byte
+		if signKey != "" && path != "-" {
+			signature, err = signReport(data)
+			if err != nil {
+				return err
+			}
+		}
+
+		if remote {
+			err = uploadOutput(path, data, outputContentTypes[format])
+			if err == nil && signature != nil {
+				err = uploadOutput(path+".sig", signature, "text/plain; charset=utf-8")
+			}
+			if err != nil {
+				log.Println("Unable to upload output:", err)
+				uploadErrs = append(uploadErrs, fmt.Errorf("unable to upload output: %s", err))
+			}
+			continue
+		}
+		if path == "-" {
+			_, err = os.Stdout.Write(data)
+		} else {
+			err = writeFileAtomic(path, data)
+		}
+		if err == nil && signature != nil {
+			err = writeFileAtomic(path+".sig", signature)
+		}
+		if err != nil {
+			return errors.Join(append(uploadErrs, fmt.Errorf("unable to write file: %s", err))...)
+		}
+	}
+	return errors.Join(uploadErrs...)
+}
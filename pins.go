@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+
+	"github.com/docker/docker/api/types/filters"
+)
+
+// Resolve the digest a remote tag currently points to
+func GetRemoteDigest(ctx context.Context, image string, tag string) (string, error) {
+	info, err := GetRemoteDockerInfo(ctx, image, tag, nil)
+	if err != nil {
+		return "", err
+	}
+	if info.Digest == "" {
+		return "", fmt.Errorf("no digest found for %s:%s", image, tag)
+	}
+	return info.Digest, nil
+}
+
+// export-pins [-o pins.json]: write the current remote digest of every image:tag used by a container
+func runExportPins(args []string) {
+	fs := flag.NewFlagSet("export-pins", flag.ExitOnError)
+	pinsPath := fs.String("o", "pins.json", "Pin file path")
+	fs.Parse(args)
+
+	resetCache()
+	containers, err := GetDockerPortainerList(filters.NewArgs())
+	if err != nil {
+		log.Fatal("Unable to get docker list:", err)
+	}
+
+	pins := make(map[string]string)
+	for _, container := range containers {
+		imageName, imageTag := parseReference(container.Image)
+		if _, ok := pins[imageName+":"+imageTag]; ok {
+			continue
+		}
+
+		digest, err := GetRemoteDigest(context.Background(), imageName, imageTag)
+		if err != nil {
+			log.Println("Unable to get remote digest:", imageName+":"+imageTag, err)
+			continue
+		}
+		pins[imageName+":"+imageTag] = digest
+		log.Printf("%s@%s", imageName+":"+imageTag, digest)
+	}
+
+	data, err := json.MarshalIndent(pins, "", "  ")
+	if err != nil {
+		log.Fatal("Unable to marshal json:", err)
+	}
+	err = os.WriteFile(*pinsPath, data, 0o644)
+	if err != nil {
+		log.Fatal("Unable to write file:", err)
+	}
+}
+
+// verify-pins [pins.json]: check that the pinned digests are still the ones the registries serve
+func runVerifyPins(args []string) {
+	pinsPath := "pins.json"
+	if len(args) > 0 {
+		pinsPath = args[0]
+	}
+
+	data, err := os.ReadFile(pinsPath)
+	if err != nil {
+		log.Fatal("Unable to read file:", err)
+	}
+	var pins map[string]string
+	err = json.Unmarshal(data, &pins)
+	if err != nil {
+		log.Fatal("Unable to unmarshal json:", err)
+	}
+
+	resetCache()
+	outdated := false
+	references := make([]string, 0, len(pins))
+	for reference := range pins {
+		references = append(references, reference)
+	}
+	slices.Sort(references)
+
+	for _, reference := range references {
+		imageName, imageTag := parseReference(reference)
+		digest, err := GetRemoteDigest(context.Background(), imageName, imageTag)
+		status := "yes"
+		if err != nil {
+			log.Println("Unable to get remote digest:", reference, err)
+			status = "unknown"
+		} else if digest != pins[reference] {
+			status = "no"
+			outdated = true
+		}
+		log.Printf("%10s %s %s -> %s", "["+status+"]", reference, pins[reference], digest)
+	}
+
+	if outdated {
+		os.Exit(1)
+	}
+}
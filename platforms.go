@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"log"
+	"slices"
+)
+
+var (
+	allPlatforms  bool
+	skipPlatforms stringList
+)
+
+// Status of one platform of a multi-platform image
+type PlatformStatus struct {
+	Platform      string `json:"platform"`  // os/arch[/variant]
+	IsLatest      string `json:"is_latest"` // yes, no, added in the latest index or removed from it
+	CurrentDigest string `json:"current_digest,omitempty"`
+	LatestDigest  string `json:"latest_digest,omitempty"`
+}
+
+// Manifest digests of the platforms of the index at digest, without attestations and -skip-platform ones,
+// nil for a single-platform image
+func platformDigests(ctx context.Context, image string, digest string) (map[string]string, error) {
+	manifest, err := GetManifest(ctx, image, digest)
+	if err != nil || len(manifest.Manifests) == 0 {
+		return nil, err
+	}
+	digests := make(map[string]string)
+	for _, entry := range manifest.Manifests {
+		// attestation manifests of buildx are listed as unknown/unknown
+		if entry.Platform.OS == "unknown" {
+			continue
+		}
+		platform := entry.Platform.OS + "/" + entry.Platform.Architecture
+		if entry.Platform.Variant != "" {
+			platform += "/" + entry.Platform.Variant
+		}
+		if !slices.Contains(skipPlatforms, platform) {
+			digests[platform] = entry.Digest
+		}
+	}
+	return digests, nil
+}
+
+// Compare every platform of the local and latest indexes, for maintainers checking that all their builds advanced
+func comparePlatforms(ctx context.Context, result *CheckResult) {
+	if result.CurrentDigest == "" || result.LatestDigest == "" {
+		return
+	}
+	image, _ := parseReference(result.Image)
+	if result.CheckedAgainst != "" {
+		image = result.CheckedAgainst
+	}
+	current, err := platformDigests(ctx, image, result.CurrentDigest)
+	if err != nil {
+		log.Println("Unable to get platforms of local image:", result.Container, err)
+		return
+	}
+	latest, err := platformDigests(ctx, image, result.LatestDigest)
+	if err != nil {
+		log.Println("Unable to get platforms of latest image:", result.Container, err)
+		return
+	}
+
+	platforms := sortedKeys(current)
+	for platform := range latest {
+		if _, ok := current[platform]; !ok {
+			platforms = append(platforms, platform)
+		}
+	}
+	slices.Sort(platforms)
+	result.PlatformStatus = nil
+	for _, platform := range platforms {
+		status := PlatformStatus{Platform: platform, CurrentDigest: current[platform], LatestDigest: latest[platform]}
+		switch {
+		case status.CurrentDigest == "":
+			status.IsLatest = "added"
+		case status.LatestDigest == "":
+			status.IsLatest = "removed"
+		case status.CurrentDigest == status.LatestDigest:
+			status.IsLatest = "yes"
+		default:
+			status.IsLatest = "no"
+		}
+		result.PlatformStatus = append(result.PlatformStatus, status)
+	}
+}
+
+// Number of platforms up to date or added, out of all of them
+func platformsLatest(statuses []PlatformStatus) int {
+	n := 0
+	for _, status := range statuses {
+		if status.IsLatest == "yes" || status.IsLatest == "added" {
+			n++
+		}
+	}
+	return n
+}
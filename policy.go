@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Container label overriding the configured update policy of its image
+const labelUpdatePolicy = "is-latest.update-policy"
+
+// Most significant severity each update policy applies, always applies every update and never none
+var updatePolicies = map[string]string{
+	"always":      "major",
+	"minor-only":  "minor",
+	"patch-only":  "patch",
+	"digest-only": "digest",
+	"never":       "",
+}
+
+// Find the update policy of a container: the label, the config entry of its image name, or the default policy
+func updatePolicy(c Container, image string) string {
+	if policy, ok := c.Labels[labelUpdatePolicy]; ok {
+		return policy
+	}
+	imageName, _ := parseReference(image)
+	if policy, ok := config.Update.Policy[imageName]; ok {
+		return policy
+	}
+	if config.Update.DefaultPolicy != "" {
+		return config.Update.DefaultPolicy
+	}
+	return "always"
+}
+
+// Check if the policy of the container allows applying the outdated result
+func policyAllows(c Container, result CheckResult) (bool, error) {
+	policy := updatePolicy(c, result.Image)
+	max, ok := updatePolicies[policy]
+	if !ok {
+		return false, fmt.Errorf("unknown update policy %q", policy)
+	}
+	if max == "" {
+		return false, nil
+	}
+	if policy == "always" {
+		return true, nil
+	}
+	// an update of unknown severity may be anything, only always applies it
+	severity := slices.Index(severities, result.Severity)
+	return severity >= 0 && severity <= slices.Index(severities, max), nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+)
+
+// Progress of a run, published as each container is checked
+type Progress struct {
+	RunID  string      `json:"run_id"`
+	Done   int         `json:"done"`  // containers checked so far
+	Total  int         `json:"total"` // containers of the run
+	Result CheckResult `json:"result"`
+}
+
+var (
+	progressMu          sync.Mutex
+	progressSubscribers = make(map[chan Progress]struct{})
+)
+
+// Subscribe to the progress of the runs, e.g. to show it live in a UI, until unsubscribe is called.
+// Progress is dropped for subscribers whose buffer is full, so a slow one never holds up a run.
+func SubscribeProgress(buffer int) (progress <-chan Progress, unsubscribe func()) {
+	ch := make(chan Progress, buffer)
+	progressMu.Lock()
+	progressSubscribers[ch] = struct{}{}
+	progressMu.Unlock()
+	return ch, func() {
+		progressMu.Lock()
+		delete(progressSubscribers, ch)
+		progressMu.Unlock()
+	}
+}
+
+// Send the progress of the run to the subscribers
+func publishProgress(p Progress) {
+	progressMu.Lock()
+	defer progressMu.Unlock()
+	for ch := range progressSubscribers {
+		select {
+		case ch <- p:
+		default:
+		}
+	}
+}
+
+// GET /api/v1/progress: the progress of the runs as server-sent events, one per checked container
+// ref: https://html.spec.whatwg.org/multipage/server-sent-events.html
+func handleProgress(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	progress, unsubscribe := SubscribeProgress(100)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case p := <-progress:
+			data, err := json.Marshal(p)
+			if err != nil {
+				log.Println("Unable to marshal progress:", err)
+				continue
+			}
+			_, err = fmt.Fprintf(w, "event: progress\ndata: %s\n\n", data)
+			if err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// File written when a directory is given as output for prom-textfile, e.g. the node_exporter textfile collector directory
+const promTextfileName = "docker_check_is_latest.prom"
+
+// Escape a Prometheus label value
+var promLabelReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// Render results in the Prometheus text exposition format
+// ref: https://prometheus.io/docs/instrumenting/exposition_formats/#text-based-format
+func renderPromTextfile(results []CheckResult) ([]byte, error) {
+	var b strings.Builder
+
+	b.WriteString("# HELP docker_check_is_latest_outdated Whether the image of the container is outdated.\n")
+	b.WriteString("# TYPE docker_check_is_latest_outdated gauge\n")
+	for _, result := range results {
+		outdated := 0
+		if result.IsLatest == "no" {
+			outdated = 1
+		}
+		fmt.Fprintf(&b, "docker_check_is_latest_outdated{container=\"%s\",host=\"%s\",image=\"%s\",status=\"%s\",error_code=\"%s\"} %d\n",
+			promLabelReplacer.Replace(strings.TrimPrefix(result.Container, "/")),
+			promLabelReplacer.Replace(result.Host),
+			promLabelReplacer.Replace(result.Image),
+			promLabelReplacer.Replace(result.IsLatest),
+			promLabelReplacer.Replace(result.ErrorCode),
+			outdated)
+	}
+
+	// once per image and compare tag, containers running the same image against the same tag share its latest version
+	b.WriteString("# HELP docker_image_remote_latest_timestamp_seconds Time the latest version of the image was pushed to its registry.\n")
+	b.WriteString("# TYPE docker_image_remote_latest_timestamp_seconds gauge\n")
+	seen := make(map[[2]string]bool)
+	for _, result := range results {
+		key := [2]string{result.Image, result.CompareTag}
+		if result.LatestPushed == nil || seen[key] {
+			continue
+		}
+		seen[key] = true
+		fmt.Fprintf(&b, "docker_image_remote_latest_timestamp_seconds{image=\"%s\",compare_tag=\"%s\"} %d\n",
+			promLabelReplacer.Replace(result.Image),
+			promLabelReplacer.Replace(result.CompareTag),
+			result.LatestPushed.Unix())
+	}
+
+	b.WriteString("# HELP docker_check_is_latest_last_run_timestamp_seconds Time of the last check.\n")
+	b.WriteString("# TYPE docker_check_is_latest_last_run_timestamp_seconds gauge\n")
+	fmt.Fprintf(&b, "docker_check_is_latest_last_run_timestamp_seconds %d\n", time.Now().Unix())
+
+	renderPromStats(&b)
+	return []byte(b.String()), nil
+}
+
+// Write data to path through a temporary file, so readers never see a partial file
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Chmod(0o644)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
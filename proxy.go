@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/docker/docker/errdefs"
+)
+
+// Check a container whose image could not be inspected by its image ID, which is the config digest of
+// the image with the classic image store or the digest of its index or manifest with the containerd one
+func checkImageID(ctx context.Context, c Container, result CheckResult, imageName string) CheckResult {
+	_, imageTag := parseReference(result.Image)
+	targetTag, err := resolveTargetTag(ctx, c, imageName, imageTag)
+	var latest ImageInfo
+	if err == nil {
+		latest, err = GetRemoteDockerInfo(ctx, imageName, targetTag, nil)
+	}
+	if targetTag != "" && targetTag != "latest" {
+		result.CompareTag = targetTag
+	}
+	if err != nil {
+		log.Println("Unable to get remote docker tag:", result.Container, imageName, err)
+		result.Error = err.Error()
+		result.ErrorCode = errorCode(err)
+		result.IsLatest = lookupStatus(err)
+		return result
+	}
+	result.LatestDigest = latest.Digest
+	result.LatestTags = strings.Join(latest.Tags, "|")
+	if !latest.Pushed.IsZero() {
+		result.LatestPushed = &latest.Pushed
+	}
+
+	same, err := sameImage(ctx, c, imageName, latest.Digest)
+	if err != nil {
+		log.Println("Unable to compare images:", result.Container, err)
+		result.Error = err.Error()
+		result.ErrorCode = errorCode(err)
+		return result
+	}
+	result.IsLatest = "no"
+	if same {
+		result.IsLatest = "yes"
+		result.CurrentTags = result.LatestTags
+	}
+	return result
+}
+
+// Check if err is the refusal of a socket proxy rather than a failure of the daemon,
+// e.g. image inspects with IMAGES=0 of tecnativa/docker-socket-proxy
+func isForbidden(err error) bool {
+	return errdefs.IsForbidden(err)
+}
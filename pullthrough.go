@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"log"
+)
+
+// Copy of the latest image in a pull-through cache
+type CacheCopy struct {
+	Registry string `json:"registry"`
+	Digest   string `json:"digest,omitempty"`
+	Behind   bool   `json:"behind"` // the cache serves another digest than upstream
+	Error    string `json:"error,omitempty"`
+}
+
+// Look up the compared tag in the pull-through cache of the upstream registry of result, e.g. a registry:2 proxy,
+// so a cache serving stale manifests is told apart from outdated containers
+func checkPullThroughCache(ctx context.Context, result *CheckResult) {
+	image, _ := parseReference(result.Image)
+	if result.CheckedAgainst != "" {
+		image = result.CheckedAgainst
+	}
+	registry, _, _ := parseImage(image)
+	cacheHost, ok := config.PullThroughCaches[registry]
+	if !ok || result.LatestDigest == "" {
+		return
+	}
+	tag := result.CompareTag
+	if tag == "" {
+		tag = "latest"
+	}
+
+	result.Cache = &CacheCopy{Registry: cacheHost}
+	info, err := GetRegistryInfo(ctx, cacheHost+"/"+registryRepository(image), tag, nil)
+	if err != nil {
+		log.Println("Unable to get image from pull-through cache:", result.Container, cacheHost, err)
+		result.Cache.Error = err.Error()
+		return
+	}
+	result.Cache.Digest = info.Digest
+	result.Cache.Behind = info.Digest != result.LatestDigest
+}
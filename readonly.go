@@ -0,0 +1,35 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+)
+
+// Refuse every mutating Docker operation, for read-only socket proxies
+var readOnly bool
+
+var errReadOnly = errors.New("read-only mode")
+
+// Error when the operation is refused by -read-only, checked by every pull, recreate, stop, restart and removal
+func checkWritable(operation string) error {
+	if readOnly {
+		return fmt.Errorf("%w: refusing to %s", errReadOnly, operation)
+	}
+	return nil
+}
+
+// Turn off the flags mutating containers and images, logging the ones that were set
+func applyReadOnly() {
+	if !readOnly {
+		return
+	}
+	if updateContainers {
+		log.Println("Ignoring -update with -read-only")
+		updateContainers = false
+	}
+	if cleanupImages {
+		log.Println("Ignoring -cleanup with -read-only")
+		cleanupImages = false
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"log"
+)
+
+// Container label overriding the configured rebuild hook of its image
+const labelRebuild = "is-latest.rebuild"
+
+// Find the rebuild hook of a container: the label, or the config entry of its image name
+func rebuildHook(c Container, image string) string {
+	if hook, ok := c.Labels[labelRebuild]; ok {
+		return hook
+	}
+	imageName, _ := parseReference(image)
+	return config.Rebuild[imageName]
+}
+
+// Run the rebuild hooks of locally built images whose base image is outdated,
+// once per image and latest base digest so a pending rebuild isn't triggered on every run
+func TriggerRebuilds(containers []Container, results []CheckResult) {
+	changed := false
+	for i, result := range results {
+		if result.IsLatest != "base-outdated" {
+			continue
+		}
+		hook := rebuildHook(containers[i], result.Image)
+		if hook == "" {
+			continue
+		}
+		if state.Rebuilds[result.Image] == result.LatestDigest {
+			results[i].Rebuild = "triggered"
+			continue
+		}
+
+		err := RunHook(hook, "rebuild", containers[i])
+		if err != nil {
+			log.Println("Unable to run rebuild hook:", result.Image, err)
+			results[i].Rebuild = "failed"
+			RecordAudit(auditResult("rebuild", result, "failed", err))
+			continue
+		}
+		RecordAudit(auditResult("rebuild", result, "triggered", nil))
+		log.Printf("%10s %s %s", "[rebuild]", result.Container, result.Image)
+		state.Rebuilds[result.Image] = result.LatestDigest
+		results[i].Rebuild = "triggered"
+		changed = true
+	}
+
+	if changed {
+		err := SaveState(statePath, state)
+		if err != nil {
+			log.Println("Unable to save state:", err)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Media types of the manifests accepted from registries
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// An image index or manifest, OCI or Docker
+// ref: https://github.com/opencontainers/image-spec/blob/main/manifest.md
+type Manifest struct {
+	MediaType   string            `json:"mediaType"`
+	Annotations map[string]string `json:"annotations"`
+	Config      struct {
+		Digest string `json:"digest"`
+		Size   int64  `json:"size"`
+	} `json:"config"`
+	Layers []struct {
+		Digest string `json:"digest"`
+		Size   int64  `json:"size"` // compressed
+	} `json:"layers"`
+	Manifests []struct {
+		Digest   string `json:"digest"`
+		Platform struct {
+			OS           string `json:"os"`
+			Architecture string `json:"architecture"`
+			Variant      string `json:"variant"`
+		} `json:"platform"`
+		Annotations map[string]string `json:"annotations"`
+	} `json:"manifests"`
+}
+
+// Image config blob, only the labels and layers are used
+type ImageConfigBlob struct {
+	Config struct {
+		Labels map[string]string `json:"Labels"`
+	} `json:"config"`
+	RootFS struct {
+		DiffIDs []string `json:"diff_ids"`
+	} `json:"rootfs"`
+}
+
+var authParamRegexp = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// Host serving the registry API of registry
+func registryHost(registry string) string {
+	if registry == "docker.io" {
+		return "registry-1.docker.io"
+	}
+	return registry
+}
+
+// Base URL of the registry, plain HTTP for registries configured with "scheme": "http"
+func registryURL(registry string) string {
+	scheme := "https"
+	if registryConfig(registry).Scheme == "http" {
+		scheme = "http"
+	}
+	return scheme + "://" + registryHost(registry)
+}
+
+// Repository path of image in its registry, e.g. library/nginx or team/app of registry.internal:5000/team/app
+func registryRepository(image string) string {
+	registry, namespace, name := parseImage(image)
+	if path, ok := strings.CutPrefix(image, registry+"/"); ok {
+		return path
+	}
+	return namespace + "/" + name
+}
+
+// Get a pull token for the repository from the realm announced by the registry
+// ref: https://distribution.github.io/distribution/spec/auth/token/
+func registryToken(ctx context.Context, registry string, repository string) (string, error) {
+	resp, err := httpFetch(ctx, registryURL(registry)+"/v2/", nil)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusUnauthorized {
+		return "", nil // no authentication required
+	}
+
+	challenge := resp.Header.Get("Www-Authenticate")
+	if !strings.HasPrefix(challenge, "Bearer ") {
+		return "", fmt.Errorf("%w: unsupported authentication of %s: %s", errAuthRequired, registry, challenge)
+	}
+	params := make(map[string]string)
+	for _, m := range authParamRegexp.FindAllStringSubmatch(challenge, -1) {
+		params[m[1]] = m[2]
+	}
+
+	query := url.Values{"scope": {"repository:" + repository + ":pull"}}
+	if params["service"] != "" {
+		query.Set("service", params["service"])
+	}
+	headers := make(http.Header)
+	if rc := registryConfig(registry); rc.Username != "" {
+		headers.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(rc.Username+":"+rc.Password)))
+	} else if registry == "ghcr.io" && ghcr_token != "" {
+		headers.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte("token:"+ghcr_token)))
+	} else if registry == "docker.io" && dockerHubUsername != "" && dockerHubToken != "" {
+		headers.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(dockerHubUsername+":"+dockerHubToken)))
+	} else if c := getStoredCredentials(registry); c != nil {
+		headers.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(c.Username+":"+c.Secret)))
+	}
+	resp, err = httpFetch(ctx, params["realm"]+"?"+query.Encode(), headers)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("error while getting token of %s: %d %s", repository, resp.StatusCode, string(resp.Body))
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	err = json.Unmarshal(resp.Body, &token)
+	if err != nil {
+		return "", fmt.Errorf("server error while unmarshalling body: %s", err)
+	}
+	if token.Token == "" {
+		return token.AccessToken, nil
+	}
+	return token.Token, nil
+}
+
+// Get a path of the registry API of image, e.g. a manifest, blob or tag list
+func registryFetch(ctx context.Context, image string, path string, accept []string) (HTTPResponse, error) {
+	return registryRequest(ctx, "GET", image, path, accept)
+}
+
+// Send a GET or HEAD request to a path of the registry API of image, failing over between the mirrors of its registry
+func registryRequest(ctx context.Context, method string, image string, path string, accept []string) (HTTPResponse, error) {
+	registry, _, _ := parseImage(image)
+	endpoints := registryEndpoints(registry)
+	var resp HTTPResponse
+	var err error
+	for i, endpoint := range endpoints {
+		resp, err = endpointRequest(ctx, endpoint, method, image, path, accept)
+		if err == nil {
+			return resp, nil
+		}
+		if i < len(endpoints)-1 {
+			logFailover(endpoint, err)
+		}
+	}
+	return resp, err
+}
+
+// Send a GET or HEAD request to a path of the registry API of image on one endpoint of its registry
+func endpointRequest(ctx context.Context, endpoint registryEndpoint, method string, image string, path string, accept []string) (HTTPResponse, error) {
+	registry := endpoint.host
+	repository := endpoint.repository(registryRepository(image))
+
+	token, err := registryToken(ctx, registry, repository)
+	if err != nil {
+		return HTTPResponse{}, err
+	}
+	headers := make(http.Header)
+	if token != "" {
+		headers.Set("Authorization", "Bearer "+token)
+	}
+	if len(accept) > 0 {
+		headers.Set("Accept", strings.Join(accept, ", "))
+	}
+
+	resp, err := httpRequest(ctx, method, registryURL(registry)+"/v2/"+repository+"/"+path, headers)
+	if err != nil {
+		return resp, err
+	}
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		return resp, fmt.Errorf("%w: %s", errNotFound, image+"/"+path)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return resp, fmt.Errorf("%w: %d while getting %s of %s", errAuthRequired, resp.StatusCode, path, image)
+	case http.StatusTooManyRequests:
+		return resp, fmt.Errorf("%w: while getting %s of %s", errRateLimited, path, image)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return resp, fmt.Errorf("error while getting %s of %s: %d", path, image, resp.StatusCode)
+	}
+	return resp, nil
+}
+
+// Get a manifest or blob of image from its registry
+func registryGet(ctx context.Context, image string, path string, accept []string) ([]byte, error) {
+	resp, err := registryFetch(ctx, image, path, accept)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Get the manifest of image at reference, a tag or digest
+func GetManifest(ctx context.Context, image string, reference string) (Manifest, error) {
+	var m Manifest
+	body, err := registryGet(ctx, image, "manifests/"+reference, manifestMediaTypes)
+	if err != nil {
+		return m, err
+	}
+	err = json.Unmarshal(body, &m)
+	if err != nil {
+		return m, fmt.Errorf("server error while unmarshalling body: %s", err)
+	}
+	return m, nil
+}
+
+// Get the config blob of an image manifest
+func GetImageConfig(ctx context.Context, image string, digest string) (ImageConfigBlob, error) {
+	var c ImageConfigBlob
+	body, err := registryGet(ctx, image, "blobs/"+digest, nil)
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(body, &c)
+	if err != nil {
+		return c, fmt.Errorf("server error while unmarshalling body: %s", err)
+	}
+	return c, nil
+}
+
+// Resolve the digest of a tag of an image in a registry of the "registry" type, checked against digests when given
+func GetRegistryInfo(ctx context.Context, image string, tag string, digests []string) (ImageInfo, error) {
+	resp, err := registryFetch(ctx, image, "manifests/"+tag, manifestMediaTypes)
+	if err != nil {
+		return ImageInfo{}, err
+	}
+
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return ImageInfo{}, fmt.Errorf("no digest returned for %s:%s", image, tag)
+	}
+	if digests != nil && !slices.Contains(digests, image+"@"+digest) {
+		return ImageInfo{}, fmt.Errorf("%w: %s:%s no longer points to the local digest", errNotFound, image, tag)
+	}
+	return ImageInfo{Digest: digest, Tags: []string{tag}}, nil
+}
+
+// Resolve the index digest of a tag of a Docker Hub image with a manifest HEAD request on the registry API,
+// which doesn't count against the pull rate limit, instead of the Hub REST API
+func GetDockerHubRegistryInfo(ctx context.Context, image string, tag string) (ImageInfo, error) {
+	resp, err := registryRequest(ctx, "HEAD", image, "manifests/"+tag, manifestMediaTypes)
+	if err != nil {
+		return ImageInfo{}, err
+	}
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return ImageInfo{}, fmt.Errorf("no digest returned for %s:%s", image, tag)
+	}
+	return ImageInfo{Digest: digest}, nil
+}
+
+// List the tags of an image in a registry of the "registry" type
+func GetRegistryTags(ctx context.Context, image string) ([]string, error) {
+	body, err := registryGet(ctx, image, "tags/list", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var list struct {
+		Tags []string `json:"tags"`
+	}
+	err = json.Unmarshal(body, &list)
+	if err != nil {
+		return nil, fmt.Errorf("server error while unmarshalling body: %s", err)
+	}
+	return list.Tags, nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Check if a key of the registries block is a host pattern, e.g. *.corp.example.com
+func isRegistryPattern(key string) bool {
+	return strings.ContainsAny(key, "*?[")
+}
+
+// Config of a registry host: its entry, or the entry of the longest pattern matching it
+func lookupRegistryConfig(host string) (RegistryConfig, bool) {
+	if rc, ok := config.Registries[host]; ok {
+		return rc, true
+	}
+	var patterns []string
+	for key := range config.Registries {
+		if matched, _ := path.Match(key, host); matched && isRegistryPattern(key) {
+			patterns = append(patterns, key)
+		}
+	}
+	if len(patterns) == 0 {
+		return RegistryConfig{}, false
+	}
+	slices.SortFunc(patterns, func(a, b string) int { return len(b) - len(a) })
+	return config.Registries[patterns[0]], true
+}
+
+func registryConfig(host string) RegistryConfig {
+	rc, _ := lookupRegistryConfig(host)
+	return rc
+}
+
+// Config of the registry a request goes to, also the one of the parent domains of its host,
+// with the APIs of Docker Hub served by docker.com hosts under the docker.io entry
+func requestRegistryConfig(req *http.Request) (RegistryConfig, bool) {
+	if rc, ok := lookupRegistryConfig(req.URL.Host); ok {
+		return rc, true
+	}
+	host := req.URL.Hostname()
+	if strings.HasSuffix(host, ".docker.com") || host == "docker.com" {
+		return lookupRegistryConfig("docker.io")
+	}
+	for domain := host; strings.Contains(domain, "."); {
+		_, domain, _ = strings.Cut(domain, ".")
+		if rc, ok := lookupRegistryConfig(domain); ok {
+			return rc, true
+		}
+	}
+	return RegistryConfig{}, false
+}
+
+// Image of record of a mirror registry configured with mirror_of, e.g. docker.io/library/nginx of cache.example.com/library/nginx
+func registryMirrorOf(imageName string) string {
+	registry, _, _ := parseImage(imageName)
+	rc := registryConfig(registry)
+	repository, ok := strings.CutPrefix(imageName, registry+"/")
+	if rc.MirrorOf == "" || !ok {
+		return ""
+	}
+	return familiarName(rc.MirrorOf + "/" + repository)
+}
+
+// Fill the tokens of the built-in registries missing from the flags with their registries entries
+func applyRegistryCredentials() {
+	if rc, ok := config.Registries["ghcr.io"]; ok && ghcr_token == "" {
+		ghcr_token = rc.Token
+		if ghcr_token == "" {
+			ghcr_token = rc.Password
+		}
+	}
+	if rc, ok := config.Registries["docker.io"]; ok && dockerHubUsername == "" && dockerHubToken == "" {
+		dockerHubUsername, dockerHubToken = rc.Username, rc.Password
+		if dockerHubToken == "" {
+			dockerHubToken = rc.Token
+		}
+	}
+}
+
+// Transports of the registries with TLS settings, by registry host
+var (
+	registryTransports   = make(map[string]*http.Transport)
+	registryTransportsMu sync.Mutex
+)
+
+// Transport of the requests to the registry of req, with its ca_file and insecure_skip_verify
+func transportFor(req *http.Request) (*http.Transport, error) {
+	rc, _ := requestRegistryConfig(req)
+	if rc.CAFile == "" && !rc.InsecureSkipVerify {
+		return transport, nil
+	}
+
+	registryTransportsMu.Lock()
+	defer registryTransportsMu.Unlock()
+	if t, ok := registryTransports[req.URL.Host]; ok {
+		return t, nil
+	}
+	tlsConfig := &tls.Config{InsecureSkipVerify: rc.InsecureSkipVerify}
+	if rc.CAFile != "" {
+		pem, err := os.ReadFile(rc.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("error while reading ca_file: %s", err)
+		}
+		tlsConfig.RootCAs, err = x509.SystemCertPool()
+		if err != nil {
+			tlsConfig.RootCAs = x509.NewCertPool()
+		}
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate in ca_file %s", rc.CAFile)
+		}
+	}
+	t := transport.Clone()
+	t.TLSClientConfig = tlsConfig
+	registryTransports[req.URL.Host] = t
+	return t, nil
+}
+
+// Last request time by host, for the rate_limit of the registries
+var (
+	lastRequests   = make(map[string]time.Time)
+	lastRequestsMu sync.Mutex
+)
+
+// Wait until the next request to the registry of req is within its rate_limit, in requests per second
+func throttle(req *http.Request) {
+	rc, _ := requestRegistryConfig(req)
+	if rc.RateLimit <= 0 {
+		return
+	}
+	lastRequestsMu.Lock()
+	next := lastRequests[req.URL.Host].Add(time.Duration(float64(time.Second) / rc.RateLimit))
+	wait := time.Until(next)
+	if wait < 0 {
+		next = time.Now()
+	}
+	lastRequests[req.URL.Host] = next
+	lastRequestsMu.Unlock()
+	time.Sleep(wait)
+}
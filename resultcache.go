@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// How long the registry lookups of an unchanged image are reused by the next runs, 0 to look up every image at every run
+var fullRefresh time.Duration
+
+// The lookup and enrichment of an image by a previous run
+type cachedLookup struct {
+	checked  CheckResult
+	enriched *CheckResult
+	requests []lookupRequest // revalidated before the lookup is reused
+	at       time.Time       // when the registries were looked up
+}
+
+// A request sent by the lookup of an image
+type lookupRequest struct {
+	method  string
+	url     string
+	headers http.Header
+}
+
+// Requests of a lookup, collected from its context by httpRequest
+type lookupRecorder struct {
+	mu       sync.Mutex
+	requests []lookupRequest
+}
+
+type lookupRecorderKey struct{}
+
+// Context recording the requests sent with it
+func recordLookups(ctx context.Context) (context.Context, *lookupRecorder) {
+	recorder := &lookupRecorder{}
+	return context.WithValue(ctx, lookupRecorderKey{}, recorder), recorder
+}
+
+// Record a request in the recorder of ctx, if any
+func recordRequest(ctx context.Context, method string, url string, headers http.Header) {
+	recorder, ok := ctx.Value(lookupRecorderKey{}).(*lookupRecorder)
+	if !ok {
+		return
+	}
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	recorder.requests = append(recorder.requests, lookupRequest{method: method, url: url, headers: headers.Clone()})
+}
+
+func (r *lookupRecorder) recorded() []lookupRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.requests)
+}
+
+// Send the requests of a lookup again, conditionally: the lookup still holds when every response is 304 Not Modified.
+// HEAD requests and responses without validators can't tell, the image is looked up again then.
+func revalidated(ctx context.Context, requests []lookupRequest) bool {
+	if len(requests) == 0 {
+		return false
+	}
+	if checkTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, checkTimeout)
+		defer cancel()
+	}
+	for _, r := range requests {
+		if r.method != "GET" {
+			return false
+		}
+		resp, err := httpFetch(ctx, r.url, r.headers)
+		if err != nil || !resp.NotModified {
+			return false
+		}
+	}
+	return true
+}
+
+// Lookups of previous runs by checkKey, which changes with the image ID of the container
+var (
+	lookupCacheMu sync.Mutex
+	lookupCache   = make(map[string]cachedLookup)
+)
+
+// The lookups and enrichments of previous runs younger than -full_refresh, and the requests to revalidate them with,
+// dropping the older ones
+func cachedLookups(now time.Time) (checked map[string]CheckResult, enriched map[string]CheckResult, requests map[string][]lookupRequest) {
+	checked = make(map[string]CheckResult)
+	enriched = make(map[string]CheckResult)
+	requests = make(map[string][]lookupRequest)
+	lookupCacheMu.Lock()
+	defer lookupCacheMu.Unlock()
+	for key, lookup := range lookupCache {
+		if now.Sub(lookup.at) >= fullRefresh {
+			delete(lookupCache, key)
+			continue
+		}
+		checked[key] = lookup.checked
+		if lookup.enriched != nil {
+			enriched[key] = *lookup.enriched
+		}
+		requests[key] = lookup.requests
+	}
+	return checked, enriched, requests
+}
+
+// Keep the lookups of a run and their requests for the next ones, except those that failed, which are retried.
+// Lookups reused from previous runs keep the time they were made at.
+func storeLookups(checked map[string]CheckResult, enriched map[string]CheckResult, requests map[string][]lookupRequest, now time.Time) {
+	if fullRefresh <= 0 {
+		return
+	}
+	lookupCacheMu.Lock()
+	defer lookupCacheMu.Unlock()
+	for key, result := range checked {
+		if slices.Contains(statusGroups["unknown"], result.IsLatest) {
+			delete(lookupCache, key)
+			continue
+		}
+		// a lookup of a previous run the run had no container for, e.g. when checking on a Docker event, stays as it is
+		if _, ok := requests[key]; !ok {
+			continue
+		}
+		lookup := cachedLookup{checked: result, requests: requests[key], at: now}
+		if previous, ok := lookupCache[key]; ok {
+			lookup.at = previous.at
+		}
+		if e, ok := enriched[key]; ok {
+			lookup.enriched = &e
+		}
+		lookupCache[key] = lookup
+	}
+}
+
+// Forget the lookup of a key, e.g. when its responses changed
+func forgetLookup(key string) {
+	lookupCacheMu.Lock()
+	defer lookupCacheMu.Unlock()
+	delete(lookupCache, key)
+}
+
+// Forget the lookups of repository, e.g. when a push was announced for it
+func forgetLookups(repository string) {
+	lookupCacheMu.Lock()
+	defer lookupCacheMu.Unlock()
+	for key, lookup := range lookupCache {
+		if imageName, _ := parseReference(lookup.checked.Image); imageName == repository || lookup.checked.CheckedAgainst == repository {
+			delete(lookupCache, key)
+		}
+	}
+}
+
+// Forget every lookup, e.g. when the config changed how images are compared
+func forgetAllLookups() {
+	lookupCacheMu.Lock()
+	defer lookupCacheMu.Unlock()
+	clear(lookupCache)
+}
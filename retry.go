@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Returned without a request once a registry failed breakerThreshold times in a row during the run
+var errRegistryUnavailable = errors.New("registry unavailable")
+
+const (
+	httpRetries      = 3                      // attempts after the first one for transient errors
+	httpRetryBackoff = 500 * time.Millisecond // doubled after each attempt
+	breakerThreshold = 3                      // consecutive failures before a registry is short-circuited
+)
+
+// Check if a status code is worth retrying
+func isTransient(statusCode int) bool {
+	return statusCode >= 500
+}
+
+// Send req, retrying transport errors and 5xx responses with exponential backoff
+func doWithRetry(client *http.Client, req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	cacheMu.Lock()
+	failures := cache.RegistryFailures[host]
+	cacheMu.Unlock()
+	if failures >= breakerThreshold {
+		return nil, fmt.Errorf("%w: %s failed %d times in a row", errRegistryUnavailable, host, failures)
+	}
+
+	backoff := httpRetryBackoff
+	for attempt := 0; ; attempt++ {
+		throttle(req)
+		countRequest(host)
+		resp, err := client.Do(req)
+		if err == nil && !isTransient(resp.StatusCode) {
+			cacheMu.Lock()
+			cache.RegistryFailures[host] = 0
+			cacheMu.Unlock()
+			return resp, nil
+		}
+		if err == nil {
+			resp.Body.Close()
+			err = fmt.Errorf("server error %s", resp.Status)
+		}
+		// a cancelled check is no failure of the registry
+		if req.Context().Err() != nil {
+			return nil, err
+		}
+		if attempt == httpRetries {
+			cacheMu.Lock()
+			cache.RegistryFailures[host]++
+			cacheMu.Unlock()
+			return nil, err
+		}
+		// a cancelled check doesn't wait for the next attempt
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+// ID of the current run, in its results and notifications
+var runID string
+
+// Random version 4 UUID
+// ref: https://www.rfc-editor.org/rfc/rfc9562#name-uuid-version-4
+func newRunID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // RFC 9562 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// An outdated image reference in a scanned file
+type fileFinding struct {
+	Path      string // relative to the scanned directory, with slashes
+	Line      int
+	Column    int
+	Reference string
+	Latest    string
+}
+
+// Minimal SARIF 2.1.0 log, as uploaded to GitHub code scanning
+// ref: https://docs.github.com/en/code-security/code-scanning/integrating-with-code-scanning/sarif-support-for-code-scanning
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool struct {
+		Driver struct {
+			Name           string      `json:"name"`
+			InformationURI string      `json:"informationUri"`
+			Rules          []sarifRule `json:"rules"`
+		} `json:"driver"`
+	} `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+	Help             sarifMessage `json:"help"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation struct {
+		ArtifactLocation struct {
+			URI string `json:"uri"`
+		} `json:"artifactLocation"`
+		Region struct {
+			StartLine   int `json:"startLine"`
+			StartColumn int `json:"startColumn"`
+		} `json:"region"`
+	} `json:"physicalLocation"`
+}
+
+const sarifRuleOutdated = "outdated-image"
+
+// Render the outdated references found in files as a SARIF log
+func renderSARIF(findings []fileFinding) ([]byte, error) {
+	run := sarifRun{Results: []sarifResult{}}
+	run.Tool.Driver.Name = commandName
+	run.Tool.Driver.InformationURI = "https://github.com/baohuiming/docker-check-is-latest"
+	run.Tool.Driver.Rules = []sarifRule{{
+		ID:               sarifRuleOutdated,
+		ShortDescription: sarifMessage{"Image reference is outdated"},
+		Help:             sarifMessage{"The tag or digest no longer points to the latest image, rewrite-compose -write updates it."},
+	}}
+	for _, f := range findings {
+		var location sarifLocation
+		location.PhysicalLocation.ArtifactLocation.URI = f.Path
+		location.PhysicalLocation.Region.StartLine = f.Line
+		location.PhysicalLocation.Region.StartColumn = f.Column
+		run.Results = append(run.Results, sarifResult{
+			RuleID:    sarifRuleOutdated,
+			Level:     "warning",
+			Message:   sarifMessage{fmt.Sprintf("%s is outdated, the latest is %s", f.Reference, f.Latest)},
+			Locations: []sarifLocation{location},
+		})
+	}
+
+	data, err := json.MarshalIndent(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal sarif: %s", err)
+	}
+	return data, nil
+}
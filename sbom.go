@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"slices"
+)
+
+var sbomDiff bool
+
+type SyftReport struct {
+	Artifacts []struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	} `json:"artifacts"`
+}
+
+type SBOMDiff struct {
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+	Updated []string `json:"updated"`
+}
+
+// Generate the package list of source (e.g. docker:sha256:..., registry:nginx@sha256:...) with syft
+func GetPackages(ctx context.Context, source string, platform string) (map[string]string, error) {
+	out, err := exec.CommandContext(ctx, "syft", source, "--quiet", "--output", "json", "--platform", platform).Output()
+	if err != nil {
+		return nil, fmt.Errorf("error while running syft on %s: %s", source, err)
+	}
+
+	var report SyftReport
+	err = json.Unmarshal(out, &report)
+	if err != nil {
+		return nil, fmt.Errorf("error while unmarshalling syft report: %s", err)
+	}
+
+	packages := make(map[string]string, len(report.Artifacts))
+	for _, a := range report.Artifacts {
+		packages[a.Name] = a.Version
+	}
+	return packages, nil
+}
+
+// Compare the packages of the local image with the remote latest image
+func DiffSBOM(ctx context.Context, container Container, image string, latestDigest string) (*SBOMDiff, error) {
+	platform := container.ImageInspect.Os + "/" + container.ImageInspect.Architecture
+
+	current, err := GetPackages(ctx, "docker:"+container.ImageID, platform)
+	if err != nil {
+		return nil, err
+	}
+	latest, err := GetPackages(ctx, "registry:"+image+"@"+latestDigest, platform)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &SBOMDiff{}
+	for name, version := range latest {
+		if currentVersion, ok := current[name]; !ok {
+			diff.Added = append(diff.Added, name+" "+version)
+		} else if currentVersion != version {
+			diff.Updated = append(diff.Updated, name+" "+currentVersion+" -> "+version)
+		}
+	}
+	for name, version := range current {
+		if _, ok := latest[name]; !ok {
+			diff.Removed = append(diff.Removed, name+" "+version)
+		}
+	}
+	slices.Sort(diff.Added)
+	slices.Sort(diff.Removed)
+	slices.Sort(diff.Updated)
+	return diff, nil
+}
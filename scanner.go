@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+)
+
+var (
+	scanner      string
+	scanSeverity = []string{"CRITICAL", "HIGH", "MEDIUM", "LOW"}
+	scanCache    = make(map[string]map[string]int)
+	scanCacheMu  sync.Mutex
+)
+
+type TrivyReport struct {
+	Results []struct {
+		Vulnerabilities []struct {
+			Severity string `json:"Severity"`
+		} `json:"Vulnerabilities"`
+	} `json:"Results"`
+}
+
+type GrypeReport struct {
+	Matches []struct {
+		Vulnerability struct {
+			Severity string `json:"severity"`
+		} `json:"vulnerability"`
+	} `json:"matches"`
+}
+
+// Run the configured vulnerability scanner against a local image and count CVEs per severity
+func ScanImage(ctx context.Context, image string) (map[string]int, error) {
+	scanCacheMu.Lock()
+	v, ok := scanCache[image]
+	scanCacheMu.Unlock()
+	if ok {
+		return v, nil
+	}
+
+	counts := make(map[string]int)
+
+	switch scanner {
+	case "trivy":
+		out, err := exec.CommandContext(ctx, "trivy", "image", "--quiet", "--format", "json", image).Output()
+		if err != nil {
+			return nil, fmt.Errorf("error while running trivy: %s", err)
+		}
+
+		var report TrivyReport
+		err = json.Unmarshal(out, &report)
+		if err != nil {
+			return nil, fmt.Errorf("error while unmarshalling trivy report: %s", err)
+		}
+		for _, r := range report.Results {
+			for _, v := range r.Vulnerabilities {
+				counts[strings.ToUpper(v.Severity)]++
+			}
+		}
+	case "grype":
+		out, err := exec.CommandContext(ctx, "grype", image, "--quiet", "--output", "json").Output()
+		if err != nil {
+			return nil, fmt.Errorf("error while running grype: %s", err)
+		}
+
+		var report GrypeReport
+		err = json.Unmarshal(out, &report)
+		if err != nil {
+			return nil, fmt.Errorf("error while unmarshalling grype report: %s", err)
+		}
+		for _, m := range report.Matches {
+			counts[strings.ToUpper(m.Vulnerability.Severity)]++
+		}
+	default:
+		return nil, fmt.Errorf("not support scanner %s", scanner)
+	}
+
+	scanCacheMu.Lock()
+	scanCache[image] = counts
+	scanCacheMu.Unlock()
+	return counts, nil
+}
+
+// Weight results so that outdated and vulnerable containers come first
+func scanRank(result CheckResult) []int {
+	rank := make([]int, 0, len(scanSeverity)+1)
+	if result.IsLatest == "no" {
+		rank = append(rank, 1)
+	} else {
+		rank = append(rank, 0)
+	}
+	for _, severity := range scanSeverity {
+		rank = append(rank, result.Vulnerabilities[severity])
+	}
+	return rank
+}
+
+// Sort outdated and vulnerable containers to the top, keeping the original order otherwise
+func sortByVulnerabilities(results []CheckResult) {
+	slices.SortStableFunc(results, func(a, b CheckResult) int {
+		return slices.Compare(scanRank(b), scanRank(a))
+	})
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+var (
+	ghcrTokenFile string
+	grpcTokenFile string
+)
+
+// Read a secret from a file, e.g. a Docker secret mounted at /run/secrets/ghcr_token
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("error while reading secret file: %s", err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// Set the tokens given by file, which take precedence over the tokens themselves
+func loadSecretFiles() error {
+	secrets, err := readSecretFiles()
+	if err != nil {
+		return err
+	}
+	applySecrets(secrets)
+	return nil
+}
+
+// Read the tokens given by file, by the variable they set, without setting any of them
+func readSecretFiles() (map[*string]string, error) {
+	secrets := make(map[*string]string)
+	for _, secret := range []struct {
+		path  string
+		value *string
+	}{
+		{ghcrTokenFile, &ghcr_token},
+		{grpcTokenFile, &grpcToken},
+	} {
+		if secret.path == "" {
+			continue
+		}
+		value, err := readSecretFile(secret.path)
+		if err != nil {
+			return nil, err
+		}
+		secrets[secret.value] = value
+	}
+	return secrets, nil
+}
+
+func applySecrets(secrets map[*string]string) {
+	for variable, value := range secrets {
+		*variable = value
+	}
+}
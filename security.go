@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Only notify about the updates of this kind: outdated for every update, security for those with security fixes
+var notifyOn string
+
+// Release notes and descriptions of security fixes
+var securityRegexp = regexp.MustCompile(`(?i)\bsecurity\b|\bCVE-\d{4}-\d{4,}\b|\bGHSA(?:-[23456789cfghjmpqrvwx]{4}){3}\b|\bvulnerabilit(?:y|ies)\b`)
+
+// A release of a GitHub repository
+// ref: https://docs.github.com/en/rest/releases/releases#list-releases
+type GitHubRelease struct {
+	TagName string `json:"tag_name"`
+	Name    string `json:"name"`
+	Body    string `json:"body"`
+}
+
+// Recent releases of a GitHub repository, most recent first
+func githubReleases(ctx context.Context, repo string) ([]GitHubRelease, error) {
+	url := "https://api.github.com/repos/" + repo + "/releases?per_page=100"
+	r, err := httpFetch(ctx, url, githubHeaders())
+	if err != nil {
+		return nil, err
+	}
+	if r.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error %d from %s", r.StatusCode, url)
+	}
+	var releases []GitHubRelease
+	err = json.Unmarshal(r.Body, &releases)
+	if err != nil {
+		return nil, fmt.Errorf("error while unmarshalling releases: %s", err)
+	}
+	return releases, nil
+}
+
+// GitHub repository of the releases of an outdated container, owner/repo or empty
+func securitySource(c Container, imageName string, result CheckResult) string {
+	if repo := releaseSource(c, imageName); repo != "" {
+		return repo
+	}
+	repo, ok := strings.CutPrefix(result.ChangelogURL, "https://github.com/")
+	if repo, ok = strings.CutSuffix(repo, "/releases"); ok && strings.Count(repo, "/") == 1 {
+		return repo
+	}
+	return ""
+}
+
+// Versions of the running and the latest image, from their version check, their OCI version or their tags
+func updateVersions(result CheckResult) (current []int, latest []int) {
+	if result.VersionCheck != nil {
+		return versionNumbers(result.VersionCheck.Current), versionNumbers(result.VersionCheck.Latest)
+	}
+	_, imageTag := parseReference(result.Image)
+	current = mostSpecificVersion(append(strings.Split(result.CurrentTags, "|"), imageTag))
+	latest = mostSpecificVersion(strings.Split(result.LatestTags, "|"))
+	if result.Current != nil && result.Current.Version != "" {
+		current = versionNumbers(result.Current.Version)
+	}
+	if result.Latest != nil && result.Latest.Version != "" {
+		latest = versionNumbers(result.Latest.Version)
+	}
+	return current, latest
+}
+
+// Releases between the running and the latest image of an outdated container marked as security fixes,
+// from the GitHub releases of its source or the description of the latest image
+func securityFixes(ctx context.Context, c Container, imageName string, result CheckResult) []string {
+	var fixes []string
+	if result.Latest != nil && securityRegexp.MatchString(result.Latest.Description) {
+		fixes = append(fixes, "image description")
+	}
+
+	repo := securitySource(c, imageName, result)
+	current, latest := updateVersions(result)
+	if repo == "" || len(current) == 0 || len(latest) == 0 {
+		return fixes
+	}
+	releases, err := githubReleases(ctx, repo)
+	if err != nil {
+		return fixes
+	}
+	for _, release := range releases {
+		version := versionNumbers(release.TagName)
+		if len(version) == 0 || slices.Compare(version, current) <= 0 || slices.Compare(version, latest) > 0 {
+			continue
+		}
+		if securityRegexp.MatchString(release.Name) || securityRegexp.MatchString(release.Body) {
+			fixes = append(fixes, repo+"@"+release.TagName)
+		}
+	}
+	return fixes
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+)
+
+// How to update the container running this tool: "skip" it, or update it "last" by handing over to the new container
+var selfUpdate string
+
+// Set by UpdateOutdated when the own container has to be updated at the end of the run
+var pendingSelfUpdate *Container
+
+// The digest UpdateSelf pulls for pendingSelfUpdate, empty to pull its tag
+var pendingSelfDigest string
+
+var (
+	mountinfoContainerIDRegexp = regexp.MustCompile(`/containers/([0-9a-f]{64})/`)
+	cgroupContainerIDRegexp    = regexp.MustCompile(`(?:docker-|/docker/)([0-9a-f]{64})`)
+)
+
+// ID of the container running this process, empty when not run in a container
+var selfContainerID = sync.OnceValue(func() string {
+	// docker bind-mounts /etc/hostname etc. from the container directory, which also works with cgroup v2
+	data, err := os.ReadFile("/proc/self/mountinfo")
+	if err == nil {
+		if m := mountinfoContainerIDRegexp.FindSubmatch(data); m != nil {
+			return string(m[1])
+		}
+	}
+	data, err = os.ReadFile("/proc/self/cgroup")
+	if err == nil {
+		if m := cgroupContainerIDRegexp.FindSubmatch(data); m != nil {
+			return string(m[1])
+		}
+	}
+	return ""
+})
+
+// Check if c is the container running this process
+func isSelf(c Container) bool {
+	id := selfContainerID()
+	return id != "" && c.ID == id
+}
+
+// Recreate the own container with its latest image, by digest when given, and start it, leaving the old one
+// for the new instance to remove
+func UpdateSelf(ctx context.Context, cli *client.Client, c Container, digest string) error {
+	err := pullUpdate(ctx, cli, c, digest)
+	if err != nil {
+		return err
+	}
+
+	info, err := cli.ContainerInspect(ctx, c.ID)
+	if err != nil {
+		return fmt.Errorf("error while inspecting container %s: %s", c.ID, err)
+	}
+
+	name := strings.TrimPrefix(info.Name, "/")
+	containerConfig, networkingConfig, endpoints := recreateConfig(info, c)
+
+	err = RunHook(containerHook(c, "pre-update"), "pre-update", c)
+	if err != nil {
+		return err
+	}
+
+	// the old container keeps running until the new instance removes it
+	oldName := fmt.Sprintf("%s-is-latest-%d", name, time.Now().Unix())
+	err = cli.ContainerRename(ctx, c.ID, oldName)
+	if err != nil {
+		return fmt.Errorf("error while renaming %s: %s", name, err)
+	}
+
+	rollback := func(cause error, newID string) error {
+		if newID != "" {
+			cli.ContainerRemove(ctx, newID, container.RemoveOptions{Force: true})
+		}
+		cli.ContainerRename(ctx, c.ID, name)
+		return cause
+	}
+
+	created, err := cli.ContainerCreate(ctx, containerConfig, info.HostConfig, networkingConfig, nil, name)
+	if err != nil {
+		return rollback(fmt.Errorf("error while creating %s: %s", name, err), "")
+	}
+	for networkName, ep := range endpoints {
+		err = cli.NetworkConnect(ctx, networkName, created.ID, ep)
+		if err != nil {
+			return rollback(fmt.Errorf("error while connecting %s to %s: %s", name, networkName, err), created.ID)
+		}
+	}
+	err = cli.ContainerStart(ctx, created.ID, container.StartOptions{})
+	if err != nil {
+		return rollback(fmt.Errorf("error while starting %s: %s", name, err), created.ID)
+	}
+
+	err = RunHook(containerHook(c, "post-update"), "post-update", c)
+	if err != nil {
+		log.Println("Unable to run post-update hook:", name, err)
+	}
+	return nil
+}
+
+// Remove the old containers left by UpdateSelf, run by the new instance
+func RemoveOldSelf(ctx context.Context) error {
+	if err := checkWritable("remove old containers"); err != nil {
+		return err
+	}
+	id := selfContainerID()
+	if id == "" {
+		return nil
+	}
+
+	cli, err := NewDockerClient(DockerEndpoint{})
+	if err != nil {
+		return err
+	}
+	info, err := cli.ContainerInspect(ctx, id)
+	if err != nil {
+		return fmt.Errorf("error while inspecting container %s: %s", id, err)
+	}
+
+	oldNameRegexp := regexp.MustCompile("^" + regexp.QuoteMeta(info.Name) + `-is-latest-\d+$`)
+	containers, err := cli.ContainerList(ctx, container.ListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("name", strings.TrimPrefix(info.Name, "/")+"-is-latest-")),
+	})
+	if err != nil {
+		return fmt.Errorf("error while listing containers: %s", err)
+	}
+	for _, c := range containers {
+		if c.ID == id || len(c.Names) == 0 || !oldNameRegexp.MatchString(c.Names[0]) {
+			continue
+		}
+		err = cli.ContainerRemove(ctx, c.ID, container.RemoveOptions{Force: true})
+		if err != nil {
+			return fmt.Errorf("error while removing old container %s: %s", c.Names[0], err)
+		}
+		log.Printf("%10s %s", "[removed]", c.Names[0])
+	}
+	return nil
+}
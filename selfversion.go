@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"slices"
+)
+
+// GitHub repository of the releases of the tool
+const toolRepository = "baohuiming/docker-check-is-latest"
+
+// version [-check]: print the version and build info, and with -check whether a newer release exists
+func runVersion(args []string) {
+	fs := flag.NewFlagSet("version", flag.ExitOnError)
+	check := fs.Bool("check", false, "Check whether a newer release is published, exiting with 1 when this one is outdated")
+	fs.Parse(args)
+	if fs.NArg() != 0 {
+		log.Fatal("Usage: version [-check]")
+	}
+
+	fmt.Printf("%s %s\n", commandName, toolVersion())
+	fmt.Printf("go: %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				fmt.Println("commit:", setting.Value)
+			case "vcs.time":
+				fmt.Println("built from:", setting.Value)
+			case "vcs.modified":
+				if setting.Value == "true" {
+					fmt.Println("modified: true")
+				}
+			}
+		}
+	}
+	if !*check {
+		return
+	}
+
+	resetCache()
+	latest, err := githubLatestRelease(context.Background(), toolRepository)
+	if err != nil {
+		log.Fatal("Unable to check the latest release:", err)
+	}
+	current := toolVersion()
+	if current == "dev" || slices.Compare(versionNumbers(current), versionNumbers(latest)) >= 0 {
+		fmt.Println("latest release:", latest, "(up-to-date)")
+		return
+	}
+	fmt.Println("latest release:", latest, "(outdated)")
+	os.Exit(1)
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// List the recent tags of a remote repository
+func remoteTagNames(ctx context.Context, image string) ([]string, error) {
+	registry, _, _ := parseImage(image)
+	switch registry {
+	case "docker.io":
+		tags, err := GetDockerHubTags(ctx, image)
+		if err != nil {
+			return nil, err
+		}
+		names := make([]string, 0, len(tags))
+		for _, tag := range tags {
+			names = append(names, tag.Name)
+		}
+		return names, nil
+	case "ghcr.io":
+		return GetGHCRTags(ctx, image)
+	}
+	switch registryConfig(registry).Type {
+	case "harbor":
+		return GetHarborTags(ctx, image)
+	case "artifactory":
+		return GetArtifactoryTags(ctx, image)
+	case "registry":
+		return GetRegistryTags(ctx, image)
+	}
+	return nil, fmt.Errorf("not support image %s", image)
+}
+
+// Numeric components of a version tag, e.g. v1.25.3-alpine -> [1 25 3]
+func versionNumbers(tag string) []int {
+	if numbers := calverNumbers(tag); numbers != nil {
+		return numbers
+	}
+	var numbers []int
+	for _, digits := range tagDigitsRegexp.FindAllString(tag, -1) {
+		n, err := strconv.Atoi(digits)
+		if err != nil {
+			return nil
+		}
+		numbers = append(numbers, n)
+	}
+	return numbers
+}
+
+// Find the highest version among tags with the same scheme as tag (e.g. 16.2 -> 17.0 but not 17.0-alpine), empty if tag has no version
+func highestVersionTag(tags []string, tag string) string {
+	if len(versionNumbers(tag)) == 0 {
+		return ""
+	}
+
+	highest := ""
+	for _, candidate := range tags {
+		if tagShape(candidate) != tagShape(tag) {
+			continue
+		}
+		if highest == "" || slices.Compare(versionNumbers(candidate), versionNumbers(highest)) > 0 {
+			highest = candidate
+		}
+	}
+	return highest
+}
+
+// Severities of an outdated result, from the least to the most significant
+var severities = []string{"digest", "patch", "minor", "major"}
+
+// The version of the most specific version tag, e.g. 1.25.3 among 1.25.3|1.25|1|latest
+func mostSpecificVersion(tags []string) []int {
+	var version []int
+	for _, tag := range tags {
+		numbers := versionNumbers(tag)
+		if len(numbers) > len(version) || (len(numbers) == len(version) && slices.Compare(numbers, version) > 0) {
+			version = numbers
+		}
+	}
+	return version
+}
+
+// Classify how far an outdated result is behind: major, minor or patch when both versions are known,
+// digest when only the digest changed (a rebuild of the same version), empty when a version is unknown
+func updateSeverity(result CheckResult) string {
+	_, imageTag := parseReference(result.Image)
+	current := mostSpecificVersion(append(strings.Split(result.CurrentTags, "|"), imageTag))
+	latest := mostSpecificVersion(strings.Split(result.LatestTags, "|"))
+	if len(current) == 0 || len(latest) == 0 {
+		return ""
+	}
+
+	// a calendar version tells when it was released, not how much changed: a new year or month is a regular release
+	if isCalVer(imageTag) || slices.ContainsFunc(strings.Split(result.LatestTags, "|"), isCalVer) {
+		switch {
+		case slices.Compare(current[:min(2, len(current))], latest[:min(2, len(latest))]) != 0:
+			return "minor"
+		case slices.Compare(current, latest) != 0:
+			return "patch"
+		}
+		return "digest"
+	}
+
+	return numbersSeverity(current, latest)
+}
+
+// How far the version numbers current are behind latest, digest when they are the same and empty when
+// they can't be compared, e.g. 1.25 against 1.25.3
+func numbersSeverity(current []int, latest []int) string {
+	if len(current) == 0 || len(latest) == 0 {
+		return ""
+	}
+	for i := 0; i < len(current) && i < len(latest); i++ {
+		if current[i] != latest[i] {
+			return []string{"major", "minor", "patch"}[min(i, 2)]
+		}
+	}
+	if len(current) != len(latest) {
+		return ""
+	}
+	return "digest"
+}
+
+// Check if severity is at least min, any severity is when min is empty
+func severityAtLeast(severity string, min string) bool {
+	return min == "" || slices.Index(severities, severity) >= slices.Index(severities, min)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"io/fs"
+	"log"
+	"net/http"
+	"slices"
+	"sync"
+)
+
+//go:embed web
+var webFiles embed.FS
+
+var (
+	listenAddr string
+	resultsMu  sync.RWMutex
+)
+
+// Copy of the latest result of every container
+func latestResults() []CheckResult {
+	resultsMu.RLock()
+	defer resultsMu.RUnlock()
+	return slices.Clone(checkResults)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		log.Println("Unable to write response:", err)
+	}
+}
+
+// GET /api/v1/results
+func handleResults(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, latestResults())
+}
+
+// GET /api/v1/fleet
+func handleFleet(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, fleetSummary())
+}
+
+// GET /api/v1/history?container=x&host=y
+func handleHistory(w http.ResponseWriter, r *http.Request) {
+	if historyPath == "" {
+		http.Error(w, "history is not enabled, start with -history", http.StatusNotFound)
+		return
+	}
+	container := r.URL.Query().Get("container")
+	if container == "" {
+		http.Error(w, "missing container", http.StatusBadRequest)
+		return
+	}
+
+	changes, err := QueryHistory(historyPath, container, r.URL.Query().Get("host"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, changes)
+}
+
+// Serve the web dashboard and its API
+func Serve(addr string) error {
+	web, err := fs.Sub(webFiles, "web")
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /", http.FileServerFS(web))
+	mux.HandleFunc("GET /api/v1/results", handleResults)
+	mux.HandleFunc("GET /api/v1/history", handleHistory)
+	mux.HandleFunc("GET /api/v1/fleet", handleFleet)
+	mux.HandleFunc("GET /api/v1/progress", handleProgress)
+	mux.HandleFunc("POST /api/v1/webhook", handleWebhook)
+	mux.HandleFunc("POST /api/v1/command", handleCommand)
+	mux.HandleFunc("POST /api/v1/telegram", handleTelegram)
+
+	log.Println("Listening on", addr)
+	return http.ListenAndServe(addr, mux)
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+)
+
+var (
+	cosignKey      string
+	cosignIdentity string
+	cosignIssuer   string
+)
+
+// Verify the cosign signature of image@digest against the configured key or keyless identity
+func VerifySignature(ctx context.Context, image string, digest string) error {
+	args := []string{"verify"}
+	if cosignKey != "" {
+		args = append(args, "--key", cosignKey)
+	} else {
+		args = append(args, "--certificate-identity", cosignIdentity, "--certificate-oidc-issuer", cosignIssuer)
+	}
+	args = append(args, image+"@"+digest)
+
+	out, err := exec.CommandContext(ctx, "cosign", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("error while verifying %s@%s: %s %s", image, digest, err, string(out))
+	}
+	return nil
+}
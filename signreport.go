@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// Cosign private key signing the outputs, each gets a detached <output>.sig
+var signKey string
+
+// Sign data with cosign sign-blob and the -sign-key, returning the base64 signature
+// ref: https://docs.sigstore.dev/cosign/signing/signing_with_blobs/
+func signReport(data []byte) ([]byte, error) {
+	f, err := os.CreateTemp("", "is-latest-report")
+	if err != nil {
+		return nil, fmt.Errorf("error while creating report file: %s", err)
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Close()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error while writing report file: %s", err)
+	}
+
+	// the password of the key is read from COSIGN_PASSWORD
+	var stderr bytes.Buffer
+	// reports can name internal hosts and images, they are not uploaded to the public transparency log
+	cmd := exec.Command("cosign", "sign-blob", "--yes", "--tlog-upload=false", "--key", signKey, f.Name())
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("error while signing report: %s %s", err, stderr.String())
+	}
+	return bytes.TrimSpace(out), nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"slices"
+)
+
+// Report the compressed sizes of outdated images, fetching their manifests
+var sizeDelta bool
+
+// Compressed sizes of the current and latest image, in bytes as pulled from the registry
+type SizeDelta struct {
+	Current int64 `json:"current,omitempty"` // unknown when the current digest isn't in the registry
+	Latest  int64 `json:"latest"`
+	Delta   int64 `json:"delta"` // latest minus current
+
+	// Layers of the latest image missing from the local image, which a pull downloads
+	DownloadLayers int   `json:"download_layers"`
+	Download       int64 `json:"download"`
+}
+
+// The size of the config and layers of an image manifest
+func manifestSize(m Manifest) int64 {
+	size := m.Config.Size
+	for _, layer := range m.Layers {
+		size += layer.Size
+	}
+	return size
+}
+
+// Compare the sizes of the current and latest image of an outdated result, for the platform of c
+func compareSizes(ctx context.Context, c Container, image string, result CheckResult) (*SizeDelta, error) {
+	latest, err := platformManifest(ctx, image, result.LatestDigest, c.ImageInspect.Os, c.ImageInspect.Architecture)
+	if err != nil {
+		return nil, err
+	}
+	size := &SizeDelta{Latest: manifestSize(latest)}
+
+	// layers are matched by their uncompressed diff IDs, the compressed digests differ when a layer was recompressed
+	config, err := GetImageConfig(ctx, image, latest.Config.Digest)
+	if err != nil {
+		return nil, err
+	}
+	for i, layer := range latest.Layers {
+		if i < len(config.RootFS.DiffIDs) && slices.Contains(c.ImageInspect.RootFS.Layers, config.RootFS.DiffIDs[i]) {
+			continue
+		}
+		size.DownloadLayers++
+		size.Download += layer.Size
+	}
+
+	if result.CurrentDigest != "" {
+		current, err := platformManifest(ctx, image, result.CurrentDigest, c.ImageInspect.Os, c.ImageInspect.Architecture)
+		if err != nil {
+			return size, err
+		}
+		size.Current = manifestSize(current)
+		size.Delta = size.Latest - size.Current
+	}
+	return size, nil
+}
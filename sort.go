@@ -0,0 +1,62 @@
+package main
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Order of the results in the outputs: name, image, status or age
+var sortOrder string
+
+var sortOrders = []string{"name", "image", "status", "age"}
+
+// Statuses from the most to the least actionable, for -sort status
+var statusOrder = []string{
+	"no", "base-outdated", "tag-removed", "repo-moved", "superseded-locally", "error", "unknown", "not-found", "registry-unavailable", "private-needs-auth",
+	"warning", "skipped-budget", "too-new", "acknowledged", "untagged", "ignored", "yes",
+}
+
+func statusRank(status string) int {
+	if i := slices.Index(statusOrder, status); i >= 0 {
+		return i
+	}
+	return len(statusOrder)
+}
+
+// Creation time of the image running the container, zero when unknown
+func imageCreated(c Container) time.Time {
+	created, _ := time.Parse(time.RFC3339Nano, c.ImageInspect.Created)
+	return created
+}
+
+// Sort results by sortOrder, by container and host within the same key so reports are the same across runs
+func sortResults(results []CheckResult) {
+	byName := func(a, b CheckResult) int {
+		return cmp.Or(
+			strings.Compare(strings.TrimPrefix(a.Container, "/"), strings.TrimPrefix(b.Container, "/")),
+			strings.Compare(a.Host, b.Host),
+		)
+	}
+	slices.SortStableFunc(results, func(a, b CheckResult) int {
+		switch sortOrder {
+		case "image":
+			return cmp.Or(strings.Compare(a.Image, b.Image), byName(a, b))
+		case "status":
+			return cmp.Or(cmp.Compare(statusRank(a.IsLatest), statusRank(b.IsLatest)), byName(a, b))
+		case "age":
+			// the oldest images first, those of unknown age last
+			switch {
+			case a.ImageCreated == nil && b.ImageCreated == nil:
+				return byName(a, b)
+			case a.ImageCreated == nil:
+				return 1
+			case b.ImageCreated == nil:
+				return -1
+			}
+			return cmp.Or(a.ImageCreated.Compare(*b.ImageCreated), byName(a, b))
+		}
+		return byName(a, b)
+	})
+}
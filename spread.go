@@ -0,0 +1,51 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Spread the registry lookups of the -interval checks across the interval
+var spreadChecks bool
+
+// Key of the containers sharing registry lookups in a run
+func checkKey(c Container) string {
+	return strings.Join([]string{c.Endpoint.Name, c.Image, c.ImageID, c.Labels[labelCompareTag]}, "\x00")
+}
+
+// Order containers by when their lookup is due within window, and return the due offsets.
+// The lookups of each registry are evenly spaced across the window, so a registry with few
+// images isn't held back by one with many.
+func spreadSchedule(containers []Container, window time.Duration) []time.Duration {
+	keys := make(map[string][]string)
+	due := make(map[string]time.Duration)
+	var registries []string
+	for _, c := range containers {
+		key := checkKey(c)
+		if _, ok := due[key]; ok {
+			continue
+		}
+		due[key] = 0
+		imageName, _ := parseReference(c.Image)
+		registry, _, _ := parseImage(imageName)
+		if _, ok := keys[registry]; !ok {
+			registries = append(registries, registry)
+		}
+		keys[registry] = append(keys[registry], key)
+	}
+	for _, registry := range registries {
+		for i, key := range keys[registry] {
+			due[key] = window * time.Duration(i) / time.Duration(len(keys[registry]))
+		}
+	}
+
+	sort.SliceStable(containers, func(i, j int) bool {
+		return due[checkKey(containers[i])] < due[checkKey(containers[j])]
+	})
+	offsets := make([]time.Duration, len(containers))
+	for i, c := range containers {
+		offsets[i] = due[checkKey(c)]
+	}
+	return offsets
+}
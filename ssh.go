@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Arguments of the ssh command running docker system dial-stdio on the host of an ssh:// URL, like the SSH
+// connection helper of the docker CLI, e.g. ssh://user@server:2222?jump=user@bastion
+// ref: https://github.com/docker/cli/blob/master/cli/connhelper/connhelper.go
+func sshArgs(host string) ([]string, error) {
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("error while parsing ssh host %s: %s", host, err)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("no host in %s", host)
+	}
+	if u.Path != "" && u.Path != "/" {
+		return nil, fmt.Errorf("ssh host %s has a path, only ssh://[user@]host[:port] is supported", host)
+	}
+
+	// BatchMode fails instead of prompting for a password nobody would type in
+	args := []string{"-T", "-o", "BatchMode=yes", "-o", "ConnectTimeout=30"}
+	if u.User != nil {
+		args = append(args, "-l", u.User.Username())
+	}
+	if u.Port() != "" {
+		args = append(args, "-p", u.Port())
+	}
+	// jump hosts, as a comma-separated list of [user@]host[:port], or ProxyJump in ~/.ssh/config
+	if jump := u.Query().Get("jump"); jump != "" {
+		args = append(args, "-J", jump)
+	}
+	return append(args, "--", u.Hostname(), "docker", "system", "dial-stdio"), nil
+}
+
+// Dial the docker daemon of an ssh:// host, every connection runs its own ssh process
+func sshDialer(host string) (func(ctx context.Context, network, addr string) (net.Conn, error), error) {
+	args, err := sshArgs(host)
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		// not bound to ctx, which only covers dialing while the connection outlives it
+		cmd := exec.Command("ssh", args...)
+		conn := &commandConn{cmd: cmd, host: host}
+		cmd.Stderr = &conn.stderr
+		var err error
+		conn.stdin, err = cmd.StdinPipe()
+		if err != nil {
+			return nil, err
+		}
+		conn.stdout, err = cmd.StdoutPipe()
+		if err != nil {
+			return nil, err
+		}
+		err = cmd.Start()
+		if err != nil {
+			return nil, fmt.Errorf("error while running ssh: %s", err)
+		}
+		return conn, nil
+	}, nil
+}
+
+// Buffer written by the command and read when it fails
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// Connection over the stdin and stdout of a command
+type commandConn struct {
+	cmd    *exec.Cmd
+	host   string
+	stdin  io.WriteCloser
+	stdout io.ReadCloser
+	stderr lockedBuffer
+
+	closeOnce sync.Once
+}
+
+func (c *commandConn) Read(p []byte) (int, error) {
+	n, err := c.stdout.Read(p)
+	// ssh exiting early, e.g. an authentication failure, explains itself on stderr
+	if errors.Is(err, io.EOF) {
+		if stderr := strings.TrimSpace(c.stderr.String()); stderr != "" {
+			return n, fmt.Errorf("ssh connection to %s closed: %s", c.host, stderr)
+		}
+	}
+	return n, err
+}
+
+func (c *commandConn) Write(p []byte) (int, error) {
+	return c.stdin.Write(p)
+}
+
+func (c *commandConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.stdin.Close()
+		c.stdout.Close()
+		c.cmd.Process.Kill()
+		c.cmd.Wait()
+	})
+	return nil
+}
+
+// The command has no addresses nor deadlines
+func (c *commandConn) LocalAddr() net.Addr                { return sshAddr(c.host) }
+func (c *commandConn) RemoteAddr() net.Addr               { return sshAddr(c.host) }
+func (c *commandConn) SetDeadline(t time.Time) error      { return nil }
+func (c *commandConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *commandConn) SetWriteDeadline(t time.Time) error { return nil }
+
+type sshAddr string
+
+func (a sshAddr) Network() string { return "ssh" }
+func (a sshAddr) String() string  { return string(a) }
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"strings"
+)
+
+// Labels set by docker compose
+// ref: https://github.com/docker/compose/blob/main/pkg/api/labels.go
+const (
+	labelComposeProject   = "com.docker.compose.project"
+	labelComposeService   = "com.docker.compose.service"
+	labelComposeDependsOn = "com.docker.compose.depends_on"
+
+	// set by docker stack deploy
+	labelStackNamespace = "com.docker.stack.namespace"
+)
+
+// Compose projects or swarm stacks to check, all containers when empty
+var projects stringList
+
+// Check if the container belongs to one of the -project compose projects or swarm stacks
+func inProjects(labels map[string]string) bool {
+	if len(projects) == 0 {
+		return true
+	}
+	for _, project := range projects {
+		if labels[labelComposeProject] == project || labels[labelStackNamespace] == project {
+			return true
+		}
+	}
+	return false
+}
+
+type Dependency struct {
+	Service string
+	Restart bool // restart this container when the dependency is updated
+}
+
+// Identify the compose service of a container as project/service, empty if not created by compose
+func serviceKey(c Container) string {
+	project, service := c.Labels[labelComposeProject], c.Labels[labelComposeService]
+	if project == "" || service == "" {
+		return ""
+	}
+	return project + "/" + service
+}
+
+// Parse the depends_on label, e.g. "db:service_healthy:true,redis:service_started:false"
+func composeDependsOn(c Container) []Dependency {
+	var deps []Dependency
+	for _, part := range strings.Split(c.Labels[labelComposeDependsOn], ",") {
+		fields := strings.Split(part, ":")
+		if fields[0] == "" {
+			continue
+		}
+		deps = append(deps, Dependency{
+			Service: fields[0],
+			Restart: len(fields) >= 3 && fields[2] == "true",
+		})
+	}
+	return deps
+}
+
+// Check if container depends on the compose service of dependency
+func dependsOn(c Container, dependency Container) (Dependency, bool) {
+	key := serviceKey(dependency)
+	if key == "" || c.Endpoint.Name != dependency.Endpoint.Name || c.Labels[labelComposeProject] != dependency.Labels[labelComposeProject] {
+		return Dependency{}, false
+	}
+	for _, dep := range composeDependsOn(c) {
+		if c.Labels[labelComposeProject]+"/"+dep.Service == key {
+			return dep, true
+		}
+	}
+	return Dependency{}, false
+}
+
+// Order container indexes so that dependencies come before their dependents, keeping the original order otherwise
+func orderByDependencies(containers []Container, indexes []int) []int {
+	ordered := make([]int, 0, len(indexes))
+	done := make(map[int]bool)
+
+	var visit func(i int, visiting map[int]bool)
+	visit = func(i int, visiting map[int]bool) {
+		if done[i] || visiting[i] { // visiting means a dependency cycle, which compose rejects anyway
+			return
+		}
+		visiting[i] = true
+		for _, j := range indexes {
+			if _, ok := dependsOn(containers[i], containers[j]); ok {
+				visit(j, visiting)
+			}
+		}
+		done[i] = true
+		ordered = append(ordered, i)
+	}
+
+	for _, i := range indexes {
+		visit(i, make(map[int]bool))
+	}
+	return ordered
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type Acknowledgement struct {
+	Until *time.Time `json:"until,omitempty"` // nil means until removed
+}
+
+// Persisted between runs in the state file
+type State struct {
+	Acknowledgements map[string]Acknowledgement `json:"acknowledgements"`    // by container name
+	Rebuilds         map[string]string          `json:"rebuilds,omitempty"`  // latest base digest a rebuild was triggered for, by image
+	Notified         map[string]time.Time       `json:"notified,omitempty"`  // last notification by image update, with the dedupe window
+	Incidents        map[string]time.Time       `json:"incidents,omitempty"` // open incidents by notifier and incident key, when they were opened
+}
+
+var (
+	statePath string
+	state     State
+)
+
+// Default location of the state file, e.g. ~/.config/docker-check-is-latest/state.json
+func defaultStatePath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "state.json"
+	}
+	return filepath.Join(dir, "docker-check-is-latest", "state.json")
+}
+
+// Read the state file, a missing file is an empty state
+func LoadState(path string) (State, error) {
+	s := State{
+		Acknowledgements: make(map[string]Acknowledgement),
+		Rebuilds:         make(map[string]string),
+		Notified:         make(map[string]time.Time),
+		Incidents:        make(map[string]time.Time),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	} else if err != nil {
+		return s, fmt.Errorf("error while reading state: %s", err)
+	}
+
+	err = json.Unmarshal(data, &s)
+	if err != nil {
+		return s, fmt.Errorf("error while unmarshalling state: %s", err)
+	}
+	if s.Acknowledgements == nil {
+		s.Acknowledgements = make(map[string]Acknowledgement)
+	}
+	if s.Rebuilds == nil {
+		s.Rebuilds = make(map[string]string)
+	}
+	if s.Notified == nil {
+		s.Notified = make(map[string]time.Time)
+	}
+	if s.Incidents == nil {
+		s.Incidents = make(map[string]time.Time)
+	}
+	return s, nil
+}
+
+func SaveState(path string, s State) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error while marshalling state: %s", err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return fmt.Errorf("error while creating state directory: %s", err)
+	}
+	err = os.WriteFile(path, data, 0o644)
+	if err != nil {
+		return fmt.Errorf("error while writing state: %s", err)
+	}
+	return nil
+}
+
+// Docker reports container names with a leading "/", the name of the endpoint follows with several hosts,
+// e.g. /nginx@tcp://10.0.0.2:2375
+func containerKey(name string, host string) string {
+	key := "/" + strings.TrimPrefix(name, "/")
+	if host != "" {
+		key += "@" + host
+	}
+	return key
+}
+
+// Check if an outdated container is acknowledged by the user
+func isAcknowledged(container string, host string, now time.Time) bool {
+	ack, ok := state.Acknowledgements[containerKey(container, host)]
+	// older state files have a zero time for forever
+	return ok && (ack.Until == nil || ack.Until.IsZero() || now.Before(*ack.Until))
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// A persistent file of the tool in a state archive, by the name of its entry
+type archivedFile struct {
+	entry string
+	path  string // empty when the file is disabled, e.g. without -history
+	flag  string
+}
+
+// Files kept in state archives: the acknowledgements and notified updates, the HTTP cache, the history and the audit log
+func archivedFiles() []archivedFile {
+	return []archivedFile{
+		{"state.json", statePath, "-state"},
+		{"http_cache.json", httpCachePath, "-http_cache"},
+		{"history.db", historyPath, "-history"},
+		{"audit.jsonl", auditPath, "-audit_log"},
+	}
+}
+
+// state export|import [flags]
+func runState(args []string) {
+	if len(args) == 0 {
+		log.Fatal("Usage: state export|import [flags]")
+	}
+	switch args[0] {
+	case "export":
+		runStateExport(args[1:])
+	case "import":
+		runStateImport(args[1:])
+	default:
+		log.Fatal("Unknown state command: ", args[0])
+	}
+}
+
+// state export [-o state.tar.gz]: archive the persistent files, e.g. to move the daemon to a new host
+func runStateExport(args []string) {
+	fs := flag.NewFlagSet("state export", flag.ExitOnError)
+	archivePath := fs.String("o", "state.tar.gz", "Archive path, - for stdout")
+	fs.Parse(args)
+
+	var out io.Writer = os.Stdout
+	if *archivePath != "-" {
+		f, err := os.Create(*archivePath)
+		if err != nil {
+			log.Fatal("Unable to create archive: ", err)
+		}
+		defer f.Close()
+		out = f
+	}
+	err := ExportState(out)
+	if err != nil {
+		log.Fatal("Unable to export state: ", err)
+	}
+}
+
+// Write the persistent files that exist to w as a gzipped tar archive
+func ExportState(w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	for _, file := range archivedFiles() {
+		if file.path == "" {
+			continue
+		}
+		data, err := readArchivedFile(file)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		err = tw.WriteHeader(&tar.Header{Name: file.entry, Mode: 0o644, Size: int64(len(data)), ModTime: time.Now()})
+		if err == nil {
+			_, err = tw.Write(data)
+		}
+		if err != nil {
+			return fmt.Errorf("error while archiving %s: %s", file.entry, err)
+		}
+		log.Println("Exported", file.path, "as", file.entry)
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("error while closing archive: %s", err)
+	}
+	return gz.Close()
+}
+
+// Read a persistent file, the history through a snapshot as the daemon may be writing to it
+func readArchivedFile(file archivedFile) ([]byte, error) {
+	if file.entry != "history.db" {
+		return os.ReadFile(file.path)
+	}
+	if _, err := os.Stat(file.path); err != nil {
+		return nil, err
+	}
+
+	db, err := OpenHistory(file.path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	dir, err := os.MkdirTemp("", "is-latest-history")
+	if err != nil {
+		return nil, fmt.Errorf("error while creating snapshot directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	snapshot := filepath.Join(dir, "history.db")
+	_, err = db.Exec("VACUUM INTO ?", snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("error while taking a snapshot of the history: %s", err)
+	}
+	return os.ReadFile(snapshot)
+}
+
+// state import [-i state.tar.gz] [-force]: restore the persistent files of an archive to the paths of the flags
+func runStateImport(args []string) {
+	fs := flag.NewFlagSet("state import", flag.ExitOnError)
+	archivePath := fs.String("i", "state.tar.gz", "Archive path, - for stdin")
+	force := fs.Bool("force", false, "Replace the files that already exist")
+	fs.Parse(args)
+
+	var in io.Reader = os.Stdin
+	if *archivePath != "-" {
+		f, err := os.Open(*archivePath)
+		if err != nil {
+			log.Fatal("Unable to open archive: ", err)
+		}
+		defer f.Close()
+		in = f
+	}
+	err := ImportState(in, *force)
+	if err != nil {
+		log.Fatal("Unable to import state: ", err)
+	}
+}
+
+// Write the files of the archive read from r to their paths, existing files are only replaced with force
+func ImportState(r io.Reader, force bool) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("error while reading archive: %s", err)
+	}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error while reading archive: %s", err)
+		}
+
+		var file *archivedFile
+		for _, f := range archivedFiles() {
+			if f.entry == header.Name {
+				file = &f
+			}
+		}
+		if file == nil {
+			log.Println("Skipped unknown entry:", header.Name)
+			continue
+		}
+		if file.path == "" {
+			log.Println("Skipped", file.entry+", set", file.flag, "to import it")
+			continue
+		}
+		if _, err := os.Stat(file.path); err == nil && !force {
+			return fmt.Errorf("%s already exists, use -force to replace it", file.path)
+		}
+
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return fmt.Errorf("error while reading %s: %s", file.entry, err)
+		}
+		err = os.MkdirAll(filepath.Dir(file.path), 0o755)
+		if err == nil {
+			err = writeFileAtomic(file.path, data)
+		}
+		if err != nil {
+			return fmt.Errorf("error while writing %s: %s", file.path, err)
+		}
+		log.Println("Imported", file.entry, "to", file.path)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Counters of cache lookups and registry calls
+type Stats struct {
+	CacheHits   map[string]int   // by cache, http or image_info
+	CacheMisses map[string]int   // by cache, http or image_info
+	Requests    map[string]int   // HTTP requests by host, retries included
+	Bytes       map[string]int64 // response body bytes by host
+}
+
+var (
+	verbose bool
+
+	// runStats is reset by every run, totalStats keeps counting while the daemon is running
+	runStats   = newStats()
+	totalStats = newStats()
+	statsMu    sync.Mutex
+)
+
+func newStats() Stats {
+	return Stats{
+		CacheHits:   make(map[string]int),
+		CacheMisses: make(map[string]int),
+		Requests:    make(map[string]int),
+		Bytes:       make(map[string]int64),
+	}
+}
+
+// Keys of m in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// Count a lookup of a cache
+func countCache(name string, hit bool) {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+	for _, s := range []Stats{runStats, totalStats} {
+		if hit {
+			s.CacheHits[name]++
+		} else {
+			s.CacheMisses[name]++
+		}
+	}
+}
+
+// Count an HTTP request to host
+func countRequest(host string) {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+	runStats.Requests[host]++
+	totalStats.Requests[host]++
+}
+
+// Count the bytes of a response body from host
+func countBytes(host string, n int) {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+	runStats.Bytes[host] += int64(n)
+	totalStats.Bytes[host] += int64(n)
+}
+
+// Log the cache and registry counters of the run
+func logStats() {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+	for _, name := range []string{"http", "image_info"} {
+		log.Printf("Cache %s: %d hits, %d misses", name, runStats.CacheHits[name], runStats.CacheMisses[name])
+	}
+	for _, host := range sortedKeys(runStats.Requests) {
+		log.Printf("Registry %s: %d requests, %d bytes", host, runStats.Requests[host], runStats.Bytes[host])
+	}
+}
+
+// Render the total counters in the Prometheus text exposition format
+func renderPromStats(b *strings.Builder) {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+	b.WriteString("# HELP docker_check_is_latest_cache_hits_total Lookups answered by a cache.\n")
+	b.WriteString("# TYPE docker_check_is_latest_cache_hits_total counter\n")
+	for _, name := range sortedKeys(totalStats.CacheHits) {
+		fmt.Fprintf(b, "docker_check_is_latest_cache_hits_total{cache=\"%s\"} %d\n", name, totalStats.CacheHits[name])
+	}
+	b.WriteString("# HELP docker_check_is_latest_cache_misses_total Lookups missing from a cache.\n")
+	b.WriteString("# TYPE docker_check_is_latest_cache_misses_total counter\n")
+	for _, name := range sortedKeys(totalStats.CacheMisses) {
+		fmt.Fprintf(b, "docker_check_is_latest_cache_misses_total{cache=\"%s\"} %d\n", name, totalStats.CacheMisses[name])
+	}
+	b.WriteString("# HELP docker_check_is_latest_registry_requests_total HTTP requests sent to a registry, retries included.\n")
+	b.WriteString("# TYPE docker_check_is_latest_registry_requests_total counter\n")
+	for _, host := range sortedKeys(totalStats.Requests) {
+		fmt.Fprintf(b, "docker_check_is_latest_registry_requests_total{registry=\"%s\"} %d\n", promLabelReplacer.Replace(host), totalStats.Requests[host])
+	}
+	b.WriteString("# HELP docker_check_is_latest_registry_response_bytes_total Bytes of the response bodies of a registry.\n")
+	b.WriteString("# TYPE docker_check_is_latest_registry_response_bytes_total counter\n")
+	for _, host := range sortedKeys(totalStats.Bytes) {
+		fmt.Fprintf(b, "docker_check_is_latest_registry_response_bytes_total{registry=\"%s\"} %d\n", promLabelReplacer.Replace(host), totalStats.Bytes[host])
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Directory of the sidecar files with the last result of every container
+var statusDir string
+
+// Characters replaced in the file names of the sidecar files, e.g. the slashes and colons of image references
+var statusFileRegexp = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+
+// Sidecar file of a container, in a directory per host for the other hosts than the default daemon
+func statusFile(dir string, result CheckResult) string {
+	name := statusFileRegexp.ReplaceAllString(strings.TrimPrefix(result.Container, "/"), "_")
+	if result.Host != "" {
+		dir = filepath.Join(dir, statusFileRegexp.ReplaceAllString(result.Host, "_"))
+	}
+	return filepath.Join(dir, name+".json")
+}
+
+// Write the result of every container to its sidecar file in dir.
+// Docker can't change the labels of a container without recreating it, so the files stand in for them.
+func WriteStatusFiles(dir string, results []CheckResult) error {
+	for _, result := range results {
+		path := statusFile(dir, result)
+		err := os.MkdirAll(filepath.Dir(path), 0o755)
+		if err != nil {
+			return fmt.Errorf("error while creating status directory: %s", err)
+		}
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error while marshalling status of %s: %s", result.Container, err)
+		}
+		err = writeFileAtomic(path, data)
+		if err != nil {
+			return fmt.Errorf("error while writing status of %s: %s", result.Container, err)
+		}
+	}
+	return nil
+}
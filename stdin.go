@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+)
+
+// Check the image references read from stdin instead of the containers of a daemon
+var readStdin bool
+
+// Read the image references of r, one per line, e.g. from docker image ls --format '{{.Repository}}:{{.Tag}}'
+func readReferences(r io.Reader) ([]string, error) {
+	var references []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		// dangling images are listed as <none>:<none>
+		if line == "" || strings.HasPrefix(line, "#") || strings.Contains(line, "<none>") {
+			continue
+		}
+		references = append(references, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error while reading stdin: %s", err)
+	}
+	return references, nil
+}
+
+// Containers standing in for the references read from stdin, a reference without digest runs what its tag points to
+func containersFromStdin() ([]Container, error) {
+	references, err := readReferences(os.Stdin)
+	if err != nil {
+		return nil, err
+	}
+
+	containers := make([]Container, 0, len(references))
+	for _, reference := range references {
+		containers = append(containers, referenceContainer(reference, reference))
+	}
+	return containers, nil
+}
+
+// Container named name standing in for reference, running its pinned digest or what its tag points to
+func referenceContainer(name string, reference string) Container {
+	imageName, imageTag := parseReference(reference)
+	c := Container{
+		Container: types.Container{Names: []string{name}, Image: reference},
+		// platform digests of Docker Hub are compared for a linux image of this architecture
+		ImageInspect: types.ImageInspect{RepoTags: []string{imageName + ":" + imageTag}, Os: "linux", Architecture: runtime.GOARCH},
+	}
+	_, digest, isPinned := strings.Cut(reference, "@")
+	if !isPinned {
+		var err error
+		digest, err = GetRemoteDigest(context.Background(), imageName, imageTag)
+		if err != nil {
+			c.InspectError = fmt.Errorf("error while resolving %s:%s: %s", imageName, imageTag, err)
+		}
+	}
+	if digest != "" {
+		c.ImageID = digest
+		c.ImageInspect.RepoDigests = []string{imageName + "@" + digest}
+	}
+	return c
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// URLs receiving a summary of every run as a JSON POST, e.g. for dashboards or a weekly digest
+var summaryWebhooks stringList
+
+// Summary of a run posted to the -summary_webhook URLs
+type RunSummary struct {
+	RunID      string            `json:"run_id"`
+	Host       string            `json:"host"`
+	StartedAt  time.Time         `json:"started_at"`
+	DurationMs int64             `json:"duration_ms"`
+	Containers int               `json:"containers"`
+	Counts     map[string]int    `json:"counts"` // by status
+	Outdated   []SummaryOutdated `json:"outdated"`
+	Errors     []SummaryError    `json:"errors"`
+}
+
+// An outdated container in a run summary
+type SummaryOutdated struct {
+	Container  string `json:"container"`
+	Host       string `json:"host,omitempty"`
+	Image      string `json:"image"`
+	Status     string `json:"status"`
+	Severity   string `json:"severity,omitempty"`
+	LatestTags string `json:"latest_tags,omitempty"`
+}
+
+// A container or endpoint a run couldn't check
+type SummaryError struct {
+	Container string `json:"container,omitempty"`
+	Host      string `json:"host,omitempty"`
+	Image     string `json:"image,omitempty"`
+	Status    string `json:"status,omitempty"`
+	Error     string `json:"error"`
+	ErrorCode string `json:"error_code,omitempty"`
+}
+
+// Summarize the results of a run started at start
+func summarizeRun(results []CheckResult, start time.Time) RunSummary {
+	summary := RunSummary{
+		RunID:      runID,
+		Host:       localHost(),
+		StartedAt:  start.UTC().Truncate(time.Second),
+		DurationMs: time.Since(start).Milliseconds(),
+		Containers: len(results),
+		Counts:     make(map[string]int),
+		Outdated:   []SummaryOutdated{},
+		Errors:     []SummaryError{},
+	}
+	for _, result := range results {
+		summary.Counts[result.IsLatest]++
+		container := strings.TrimPrefix(result.Container, "/")
+		if slices.Contains(statusGroups["outdated"], result.IsLatest) {
+			summary.Outdated = append(summary.Outdated, SummaryOutdated{Container: container, Host: result.Host, Image: result.Image,
+				Status: result.IsLatest, Severity: result.Severity, LatestTags: result.LatestTags})
+		}
+		if result.Error != "" {
+			summary.Errors = append(summary.Errors, SummaryError{Container: container, Host: result.Host, Image: result.Image,
+				Status: result.IsLatest, Error: result.Error, ErrorCode: result.ErrorCode})
+		}
+	}
+	unreachable := lastEndpointErrors()
+	for _, name := range sortedKeys(unreachable) {
+		summary.Errors = append(summary.Errors, SummaryError{Host: name, Error: unreachable[name]})
+	}
+	return summary
+}
+
+// Post the summary of a run to every -summary_webhook
+func SendRunSummary(results []CheckResult, start time.Time) {
+	body, err := json.Marshal(summarizeRun(results, start))
+	if err != nil {
+		log.Println("Unable to marshal run summary:", err)
+		return
+	}
+	for _, url := range summaryWebhooks {
+		req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/json")
+			err = sendNotification(req)
+		} else {
+			err = fmt.Errorf("error while creating request: %s", err)
+		}
+		if err != nil {
+			log.Println("Unable to send run summary:", err)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Socket of the native journald protocol
+// ref: https://systemd.io/JOURNAL_NATIVE_PROTOCOL/
+const journalSocket = "/run/systemd/journal/socket"
+
+// Send a state (e.g. "READY=1") to the service manager, a no-op when not run by systemd with Type=notify
+// ref: https://www.freedesktop.org/software/systemd/man/latest/sd_notify.html
+func sdNotify(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+	// abstract namespace socket
+	if strings.HasPrefix(socket, "@") {
+		socket = "\x00" + socket[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// Interval to send WATCHDOG=1 at, 0 when the watchdog is disabled
+func sdWatchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	// notify at half the timeout, as recommended by sd_watchdog_enabled(3)
+	return time.Duration(usec) * time.Microsecond / 2
+}
+
+// Check if the output goes to the journal, systemd sets JOURNAL_STREAM for services logging to it
+func logsToJournal() bool {
+	stream := os.Getenv("JOURNAL_STREAM")
+	if stream == "" {
+		return false
+	}
+	_, err := os.Stat(journalSocket)
+	return err == nil
+}
+
+// Send a log entry with structured fields to journald
+func journalSend(message string, priority int, fields map[string]string) error {
+	var b bytes.Buffer
+	writeField := func(key string, value string) {
+		// values with newlines are sent with their length in binary
+		if strings.Contains(value, "\n") {
+			b.WriteString(key + "\n")
+			binary.Write(&b, binary.LittleEndian, uint64(len(value)))
+			b.WriteString(value + "\n")
+			return
+		}
+		b.WriteString(key + "=" + value + "\n")
+	}
+
+	writeField("MESSAGE", message)
+	writeField("PRIORITY", strconv.Itoa(priority))
+	writeField("SYSLOG_IDENTIFIER", "docker-check-is-latest")
+	for key, value := range fields {
+		writeField(key, value)
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: journalSocket, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write(b.Bytes())
+	return err
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Container label overriding the configured tag pattern
+const labelTagPattern = "is-latest.tag-pattern"
+
+// Pattern of the acceptable tags of the image of a container, from its label or the config, empty for none
+func tagPattern(c Container, imageName string) string {
+	if pattern, ok := c.Labels[labelTagPattern]; ok && pattern != "" {
+		return pattern
+	}
+	return config.TagPatterns[imageName]
+}
+
+// Match a tag against a pattern: a regular expression when it starts with ^, a glob otherwise (e.g. 1.25.*-alpine)
+func matchTagPattern(pattern string, tag string) (bool, error) {
+	if strings.HasPrefix(pattern, "^") {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return false, fmt.Errorf("invalid tag pattern %s: %s", pattern, err)
+		}
+		return re.MatchString(tag), nil
+	}
+	matched, err := path.Match(pattern, tag)
+	if err != nil {
+		return false, fmt.Errorf("invalid tag pattern %s: %s", pattern, err)
+	}
+	return matched, nil
+}
+
+// Highest version among the remote tags of image matching pattern
+func newestMatchingTag(ctx context.Context, image string, pattern string) (string, error) {
+	tags, err := remoteTagNames(ctx, image)
+	if err != nil {
+		return "", err
+	}
+	newest := ""
+	for _, tag := range tags {
+		matched, err := matchTagPattern(pattern, tag)
+		if err != nil {
+			return "", err
+		}
+		if matched && (newest == "" || slices.Compare(versionNumbers(tag), versionNumbers(newest)) > 0) {
+			newest = tag
+		}
+	}
+	if newest == "" {
+		return "", fmt.Errorf("%w: no tag of %s matches %s", errNotFound, image, pattern)
+	}
+	return newest, nil
+}
+
+// Version tags with a variant suffix, e.g. 1.25-alpine or 3.12.4-slim-bookworm
+var variantTagRegexp = regexp.MustCompile(`^v?\d+(?:\.\d+)*-[A-Za-z]`)
+
+// Tag following the variant of tag, as latest only follows the default one: the tag itself for a variant or channel
+// without version (e.g. alpine or stable), the highest version of the same scheme for a versioned variant
+// (e.g. 1.27-alpine for 1.25-alpine), latest otherwise
+func variantTag(ctx context.Context, imageName string, imageTag string) string {
+	if imageTag != "latest" && len(versionNumbers(imageTag)) == 0 {
+		return imageTag
+	}
+	if !variantTagRegexp.MatchString(imageTag) {
+		return "latest"
+	}
+	tags, err := remoteTagNames(ctx, imageName)
+	if err != nil {
+		log.Println("Unable to list remote tags:", imageName, err)
+		return "latest"
+	}
+	if highest := highestVersionTag(tags, imageTag); highest != "" {
+		return highest
+	}
+	return "latest"
+}
+
+// Tag the image of a container is compared against: the newest one matching its tag pattern, its compare tag,
+// or the tag following the variant of its tag
+func resolveTargetTag(ctx context.Context, c Container, imageName string, imageTag string) (string, error) {
+	if pattern := tagPattern(c, imageName); pattern != "" {
+		return newestMatchingTag(ctx, imageName, pattern)
+	}
+	if _, ok := c.Labels[labelCompareTag]; ok {
+		return compareTag(c, imageName), nil
+	}
+	if _, ok := config.CompareTags[imageName]; ok {
+		return compareTag(c, imageName), nil
+	}
+	return variantTag(ctx, imageName, imageTag), nil
+}
@@ -0,0 +1,11 @@
+package main
+
+import "time"
+
+// Time zone of the timestamps in the outputs, from -timezone
+var location = time.Local
+
+// Current time in the output time zone
+func timestamp() time.Time {
+	return time.Now().In(location)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// Record how long the check of every container took
+var timings bool
+
+// Durations of the check of a container in milliseconds
+type Timings struct {
+	Inspect  int64 `json:"inspect_ms"`          // container and image inspect
+	Registry int64 `json:"registry_ms"`         // registry lookups, 0 when made for another container running the same image
+	Enrich   int64 `json:"enrich_ms,omitempty"` // release notes, signature, SBOM, scan and sizes of an outdated image
+	Total    int64 `json:"total_ms"`
+}
+
+func newTimings(inspect, registry, enrich time.Duration) *Timings {
+	return &Timings{
+		Inspect:  inspect.Milliseconds(),
+		Registry: registry.Milliseconds(),
+		Enrich:   enrich.Milliseconds(),
+		Total:    (inspect + registry + enrich).Milliseconds(),
+	}
+}
+
+// Log the total durations of a run and its slowest container
+func logTimings(results []CheckResult) {
+	var total Timings
+	slowest := -1
+	for i, result := range results {
+		if result.Timings == nil {
+			continue
+		}
+		total.Inspect += result.Timings.Inspect
+		total.Registry += result.Timings.Registry
+		total.Enrich += result.Timings.Enrich
+		total.Total += result.Timings.Total
+		if slowest < 0 || result.Timings.Total > results[slowest].Timings.Total {
+			slowest = i
+		}
+	}
+	log.Printf("Timings: inspect %dms, registry %dms, enrich %dms, total %dms", total.Inspect, total.Registry, total.Enrich, total.Total)
+	if slowest >= 0 {
+		log.Printf("Slowest: %s %s %dms", results[slowest].Container, results[slowest].Image, results[slowest].Timings.Total)
+	}
+}
@@ -0,0 +1,522 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+)
+
+// Container label overriding the configured maintenance window
+const labelMaintenanceWindow = "is-latest.maintenance-window"
+
+type UpdateConfig struct {
+	// Default maintenance window for containers without the label, updates are allowed at any time when empty
+	MaintenanceWindow string `json:"maintenance_window"`
+
+	// Hooks for containers without the hook labels, a webhook URL or a shell command
+	PreUpdate  string `json:"pre_update"`  // before stopping the old container, a failure aborts the update
+	PostUpdate string `json:"post_update"` // after starting the new container
+
+	// Update policy by image name (e.g. "nginx": "patch-only", "postgres": "never"),
+	// always, minor-only, patch-only, digest-only or never
+	Policy        map[string]string `json:"policy"`
+	DefaultPolicy string            `json:"default_policy"` // for images without a policy, always by default
+
+	// recreate, or blue-green to start a verified copy of containers with published ports first
+	Strategy string `json:"strategy"`
+
+	// What to do with paused, restarting and unhealthy containers, by state (e.g. "paused": "unpause"):
+	// skip, unpause for paused ones, or force to update them as they are
+	States map[string]string `json:"states"`
+}
+
+var (
+	updateContainers  bool
+	restartDependents bool
+	healthTimeout     time.Duration
+)
+
+// The new container failed its HEALTHCHECK and the old one was restored
+var errUnhealthy = errors.New("unhealthy")
+
+// The pulled image is the one the container runs, e.g. a pinned tag or a compare tag other than the pulled one
+var errNoNewImage = errors.New("no new image")
+
+const (
+	healthPollInterval   = 2 * time.Second
+	defaultHealthTimeout = 2 * time.Minute
+)
+
+// Drop the values the container inherited from its old image, so the new image's defaults apply
+func dropImageDefaults(config *container.Config, imageConfig *container.Config) {
+	if imageConfig == nil {
+		return
+	}
+
+	config.Env = slices.DeleteFunc(config.Env, func(env string) bool {
+		return slices.Contains(imageConfig.Env, env)
+	})
+	for k, v := range imageConfig.Labels {
+		if config.Labels[k] == v {
+			delete(config.Labels, k)
+		}
+	}
+	if slices.Equal(config.Cmd, imageConfig.Cmd) {
+		config.Cmd = nil
+	}
+	if slices.Equal(config.Entrypoint, imageConfig.Entrypoint) {
+		config.Entrypoint = nil
+	}
+	if config.WorkingDir == imageConfig.WorkingDir {
+		config.WorkingDir = ""
+	}
+	if config.User == imageConfig.User {
+		config.User = ""
+	}
+}
+
+// Reference pulled to update c, the tag its image was resolved to when it was started by image ID
+func pullReference(c Container) string {
+	if isImageID(c) {
+		return localReference(c)
+	}
+	return c.Image
+}
+
+// Digest an update of result pulls: the verified latest digest when signatures are verified, so the tag can't
+// have moved to an unverified image since, empty to pull the tag
+func updateDigest(result CheckResult) string {
+	if cosignKey == "" && cosignIdentity == "" {
+		return ""
+	}
+	return result.LatestDigest
+}
+
+// Pull the image c is recreated with, by digest when there is one and then tagged like its tag,
+// errNoNewImage when it is the image c already runs
+func pullUpdate(ctx context.Context, cli *client.Client, c Container, digest string) error {
+	ref := pullReference(c)
+	if digest == "" {
+		err := pullImage(ctx, cli, ref)
+		if err != nil {
+			return err
+		}
+	} else {
+		imageName, _ := parseReference(ref)
+		pinned := imageName + "@" + digest
+		err := pullImage(ctx, cli, pinned)
+		if err != nil {
+			return err
+		}
+		err = cli.ImageTag(ctx, pinned, ref)
+		if err != nil {
+			return fmt.Errorf("error while tagging %s as %s: %s", pinned, ref, err)
+		}
+	}
+
+	pulled, _, err := cli.ImageInspectWithRaw(ctx, ref)
+	if err != nil {
+		return fmt.Errorf("error while inspecting image %s: %s", ref, err)
+	}
+	if pulled.ID == c.ImageID {
+		return errNoNewImage
+	}
+	return nil
+}
+
+// Pull an image, waiting for the pull to complete
+func pullImage(ctx context.Context, cli *client.Client, ref string) error {
+	if err := checkWritable("pull " + ref); err != nil {
+		return err
+	}
+	reader, err := cli.ImagePull(ctx, ref, image.PullOptions{})
+	if err != nil {
+		return fmt.Errorf("error while pulling %s: %s", ref, err)
+	}
+	_, err = io.Copy(io.Discard, reader)
+	reader.Close()
+	if err != nil {
+		return fmt.Errorf("error while pulling %s: %s", ref, err)
+	}
+	return nil
+}
+
+// Build the configuration of a container recreated from info with the image of c,
+// with the networks that have to be connected after creating it
+func recreateConfig(info types.ContainerJSON, c Container) (*container.Config, *network.NetworkingConfig, map[string]*network.EndpointSettings) {
+	shortID := info.ID[:12]
+
+	containerConfig := info.Config
+	containerConfig.Image = pullReference(c)
+	if containerConfig.Hostname == shortID {
+		containerConfig.Hostname = ""
+	}
+	dropImageDefaults(containerConfig, c.ImageInspect.Config)
+
+	// only one network can be given on create with older API versions, connect the others afterwards
+	endpoints := make(map[string]*network.EndpointSettings)
+	for networkName, ep := range info.NetworkSettings.Networks {
+		endpoints[networkName] = &network.EndpointSettings{
+			IPAMConfig: ep.IPAMConfig,
+			Links:      ep.Links,
+			Aliases: slices.DeleteFunc(slices.Clone(ep.Aliases), func(alias string) bool {
+				return alias == shortID
+			}),
+			DriverOpts: ep.DriverOpts,
+		}
+	}
+	networkingConfig := &network.NetworkingConfig{EndpointsConfig: make(map[string]*network.EndpointSettings)}
+	if ep, ok := endpoints[string(info.HostConfig.NetworkMode)]; ok {
+		networkingConfig.EndpointsConfig[string(info.HostConfig.NetworkMode)] = ep
+		delete(endpoints, string(info.HostConfig.NetworkMode))
+	}
+	return containerConfig, networkingConfig, endpoints
+}
+
+// Wait for the HEALTHCHECK of a started container to pass, containers without one are healthy once started
+func waitHealthy(ctx context.Context, cli *client.Client, id string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		info, err := cli.ContainerInspect(ctx, id)
+		if err != nil {
+			return fmt.Errorf("error while inspecting container %s: %s", id, err)
+		}
+		if info.State == nil || info.State.Health == nil {
+			return nil
+		}
+
+		health := info.State.Health
+		switch {
+		case health.Status == types.Healthy:
+			return nil
+		case health.Status == types.Unhealthy:
+			reason := health.Status
+			if n := len(health.Log); n > 0 {
+				reason = strings.TrimSpace(health.Log[n-1].Output)
+			}
+			return fmt.Errorf("%w: %s", errUnhealthy, reason)
+		case !info.State.Running:
+			return fmt.Errorf("%w: exited with code %d", errUnhealthy, info.State.ExitCode)
+		case time.Now().After(deadline):
+			return fmt.Errorf("%w: not healthy after %s", errUnhealthy, timeout)
+		}
+		time.Sleep(healthPollInterval)
+	}
+}
+
+// Pull the image of the container, by digest when given, and recreate it with the same configuration, unpausing it first when asked
+func UpdateContainer(ctx context.Context, cli *client.Client, c Container, digest string, unpause bool) (err error) {
+	err = pullUpdate(ctx, cli, c, digest)
+	if err != nil {
+		return err
+	}
+
+	info, err := cli.ContainerInspect(ctx, c.ID)
+	if err != nil {
+		return fmt.Errorf("error while inspecting container %s: %s", c.ID, err)
+	}
+
+	name := strings.TrimPrefix(info.Name, "/")
+	containerConfig, networkingConfig, endpoints := recreateConfig(info, c)
+
+	err = RunHook(containerHook(c, "pre-update"), "pre-update", c)
+	if err != nil {
+		return err
+	}
+
+	// only once the update goes ahead, a deferred or refused update leaves the container paused
+	if unpause {
+		err = cli.ContainerUnpause(ctx, c.ID)
+		if err != nil {
+			return fmt.Errorf("error while unpausing %s: %s", name, err)
+		}
+		// the old container is still there when the update failed or was rolled back
+		defer func() {
+			if err == nil {
+				return
+			}
+			if err := cli.ContainerPause(ctx, c.ID); err != nil {
+				log.Println("Unable to pause container again:", name, err)
+			}
+		}()
+	}
+
+	wasRunning := info.State != nil && info.State.Running
+	if wasRunning {
+		err = cli.ContainerStop(ctx, c.ID, container.StopOptions{})
+		if err != nil {
+			return fmt.Errorf("error while stopping %s: %s", name, err)
+		}
+	}
+
+	// keep the old container around until the new one is started, to roll back on failure
+	oldName := fmt.Sprintf("%s-is-latest-%d", name, time.Now().Unix())
+	err = cli.ContainerRename(ctx, c.ID, oldName)
+	if err != nil {
+		if wasRunning {
+			cli.ContainerStart(ctx, c.ID, container.StartOptions{})
+		}
+		return fmt.Errorf("error while renaming %s: %s", name, err)
+	}
+
+	rollback := func(cause error, newID string) error {
+		if newID != "" {
+			cli.ContainerRemove(ctx, newID, container.RemoveOptions{Force: true})
+		}
+		cli.ContainerRename(ctx, c.ID, name)
+		if wasRunning {
+			cli.ContainerStart(ctx, c.ID, container.StartOptions{})
+		}
+		return cause
+	}
+
+	created, err := cli.ContainerCreate(ctx, containerConfig, info.HostConfig, networkingConfig, nil, name)
+	if err != nil {
+		return rollback(fmt.Errorf("error while creating %s: %s", name, err), "")
+	}
+	for networkName, ep := range endpoints {
+		err = cli.NetworkConnect(ctx, networkName, created.ID, ep)
+		if err != nil {
+			return rollback(fmt.Errorf("error while connecting %s to %s: %s", name, networkName, err), created.ID)
+		}
+	}
+	if wasRunning {
+		err = cli.ContainerStart(ctx, created.ID, container.StartOptions{})
+		if err != nil {
+			return rollback(fmt.Errorf("error while starting %s: %s", name, err), created.ID)
+		}
+		if healthTimeout > 0 {
+			err = waitHealthy(ctx, cli, created.ID, healthTimeout)
+			if err != nil {
+				return rollback(fmt.Errorf("error while starting %s: %w", name, err), created.ID)
+			}
+		}
+	}
+
+	err = cli.ContainerRemove(ctx, c.ID, container.RemoveOptions{})
+	if err != nil {
+		log.Println("Unable to remove old container:", oldName, err)
+	}
+
+	err = RunHook(containerHook(c, "post-update"), "post-update", c)
+	if err != nil {
+		log.Println("Unable to run post-update hook:", name, err)
+	}
+	return nil
+}
+
+// Update an outdated container if it is inside its maintenance window, returning the update status
+// and the error of a failed update. The image is pulled by digest when given. A paused container is unpaused when asked,
+// and paused again if the update fails.
+func TryUpdate(ctx context.Context, cli *client.Client, c Container, now time.Time, digest string, unpause bool) (string, error) {
+	if outcome, err := outsideWindow(c, now); outcome != "" {
+		return outcome, err
+	}
+
+	var err error
+	switch strategy := updateStrategy(c); strategy {
+	case "recreate":
+		err = UpdateContainer(ctx, cli, c, digest, unpause)
+	case "blue-green":
+		err = UpdateBlueGreen(ctx, cli, c, digest, unpause)
+	default:
+		err = fmt.Errorf("unknown update strategy %q", strategy)
+	}
+	if errors.Is(err, errNoNewImage) {
+		return "unchanged", nil
+	}
+	if errors.Is(err, errUnhealthy) {
+		log.Println("Unable to update container, rolled back:", c.Names[0], err)
+		return "rolled-back", err
+	}
+	if err != nil {
+		log.Println("Unable to update container:", c.Names[0], err)
+		return "failed", err
+	}
+	return "updated", nil
+}
+
+// Outcome of an update of c at now outside its maintenance window: deferred, failed when the window is invalid,
+// empty when the update may go ahead
+func outsideWindow(c Container, now time.Time) (string, error) {
+	window := config.Update.MaintenanceWindow
+	if label, ok := c.Labels[labelMaintenanceWindow]; ok {
+		window = label
+	}
+	if window == "" {
+		return "", nil
+	}
+	windows, err := parseMaintenanceWindows(window)
+	if err != nil {
+		log.Println("Unable to parse maintenance window:", c.Names[0], err)
+		return "failed", err
+	}
+	if !inMaintenanceWindows(windows, now) {
+		return "deferred", nil
+	}
+	return "", nil
+}
+
+// Audit entry of an action on the container of result
+func auditResult(action string, result CheckResult, outcome string, err error) AuditEntry {
+	entry := AuditEntry{
+		Action:    action,
+		Container: result.Container,
+		Host:      result.Host,
+		Image:     result.Image,
+		OldDigest: result.CurrentDigest,
+		NewDigest: result.LatestDigest,
+		Outcome:   outcome,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	return entry
+}
+
+// Update outdated containers with dependencies first, then restart the dependents of updated containers
+func UpdateOutdated(ctx context.Context, containers []Container, results []CheckResult, now time.Time) {
+	clients := make(map[string]*client.Client)
+	var outdated []int
+	for i, result := range results {
+		if result.IsLatest == "no" {
+			outdated = append(outdated, i)
+		}
+	}
+
+	for _, i := range orderByDependencies(containers, outdated) {
+		// the software updated itself in-place, pulling the image of the container would not update it
+		if results[i].VersionCheck != nil && results[i].VersionCheck.Source == "command" {
+			results[i].Update = "skipped"
+			log.Printf("%10s %s %s (checked by its version command)", "[skipped]", results[i].Container, results[i].Image)
+			continue
+		}
+
+		// an image that failed its signature verification, or couldn't be verified, is never pulled
+		if (cosignKey != "" || cosignIdentity != "") && results[i].Signature != "verified" {
+			results[i].Update = "held"
+			log.Printf("%10s %s %s (signature %s)", "[held]", results[i].Container, results[i].Image, cmp.Or(results[i].Signature, "not verified"))
+			continue
+		}
+
+		// evaluated before anything is pulled or stopped
+		allowed, err := policyAllows(containers[i], results[i])
+		if err != nil {
+			log.Println("Unable to evaluate update policy:", results[i].Container, err)
+			results[i].Update = "failed"
+			continue
+		}
+		if !allowed {
+			results[i].Update = "held"
+			log.Printf("%10s %s %s", "[held]", results[i].Container, results[i].Image)
+			continue
+		}
+		if interactiveUpdate && !confirmUpdate(results[i]) {
+			results[i].Update = "declined"
+			log.Printf("%10s %s %s", "[declined]", results[i].Container, results[i].Image)
+			continue
+		}
+
+		endpoint := containers[i].Endpoint
+		cli, ok := clients[endpoint.Name]
+		if !ok {
+			var err error
+			cli, err = NewDockerClient(endpoint)
+			if err != nil {
+				log.Println("Unable to create docker client:", endpoint.Name, err)
+				results[i].Update = "failed"
+				RecordAudit(auditResult("update", results[i], "failed", err))
+				continue
+			}
+			clients[endpoint.Name] = cli
+		}
+
+		// stopping the own container would kill this run, hand over at the end of the run instead
+		if isSelf(containers[i]) {
+			results[i].Update = "skipped"
+			if selfUpdate == "last" {
+				// the hand over is an update like any other, only inside the maintenance window
+				results[i].Update, err = outsideWindow(containers[i], now)
+				if results[i].Update == "" {
+					pendingSelfUpdate, pendingSelfDigest = &containers[i], updateDigest(results[i])
+					results[i].Update = "pending"
+				}
+			}
+			log.Printf("%10s %s %s", "["+results[i].Update+"]", results[i].Container, results[i].Image)
+			if results[i].Update != "deferred" {
+				RecordAudit(auditResult("update", results[i], results[i].Update, err))
+			}
+			continue
+		}
+
+		// a paused or crashing container would fail mid-recreation, handle it as configured
+		state, action, err := updateStateAction(containers[i])
+		if err != nil {
+			log.Println("Unable to evaluate update state:", results[i].Container, err)
+			results[i].Update = "failed"
+			RecordAudit(auditResult("update", results[i], "failed", err))
+			continue
+		}
+		if state != "" {
+			results[i].UpdateState = state + ": " + action
+			if action == "skip" {
+				results[i].Update = "skipped"
+				log.Printf("%10s %s %s (%s)", "[skipped]", results[i].Container, results[i].Image, results[i].UpdateState)
+				RecordAudit(auditResult("update", results[i], "skipped", nil))
+				continue
+			}
+		}
+
+		// a paused container is unpaused by the update itself, after the maintenance window and the pre-update hook
+		results[i].Update, err = TryUpdate(ctx, cli, containers[i], now, updateDigest(results[i]), action == "unpause")
+		log.Printf("%10s %s %s", "["+results[i].Update+"]", results[i].Container, results[i].Image)
+		// a deferred or unchanged update is no action, it is retried on the next run
+		if results[i].Update != "deferred" && results[i].Update != "unchanged" {
+			RecordAudit(auditResult("update", results[i], results[i].Update, err))
+		}
+		if results[i].Update != "updated" {
+			continue
+		}
+
+		if cleanupImages {
+			err := CleanupImages(ctx, cli, containers[i])
+			if err != nil {
+				log.Println("Unable to clean up images:", results[i].Image, err)
+			}
+		}
+
+		for j, dependent := range containers {
+			dep, ok := dependsOn(dependent, containers[i])
+			// outdated dependents are recreated after their dependencies anyway
+			if !ok || !(dep.Restart || restartDependents) || slices.Contains(outdated, j) || dependent.State != "running" {
+				continue
+			}
+
+			err := checkWritable("restart " + dependent.Names[0])
+			if err == nil {
+				err = cli.ContainerRestart(ctx, dependent.ID, container.StopOptions{})
+			}
+			entry := AuditEntry{Action: "restart", Container: dependent.Names[0], Host: results[i].Host, Image: dependent.Image, Outcome: "restarted"}
+			if err != nil {
+				log.Println("Unable to restart dependent container:", dependent.Names[0], err)
+				entry.Outcome, entry.Error = "failed", err.Error()
+				RecordAudit(entry)
+				continue
+			}
+			RecordAudit(entry)
+			log.Printf("%10s %s", "[restarted]", dependent.Names[0])
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Container label overriding the update.states action of the container, e.g. skip or force
+const labelUpdateState = "is-latest.update-state"
+
+// What an update does with a paused, restarting or unhealthy container when neither the label nor the config says.
+// A paused container can't be stopped cleanly and a restarting one may be mid-crash, an unhealthy one may be fixed by the update.
+var defaultStateActions = map[string]string{
+	"paused":     "skip",
+	"restarting": "skip",
+	"unhealthy":  "force",
+}
+
+// Actions allowed for each state, unpause lets the new container run. There is no force for a paused container,
+// the daemon refuses to stop it until it is unpaused.
+var stateActions = map[string][]string{
+	"paused":     {"skip", "unpause"},
+	"restarting": {"skip", "force"},
+	"unhealthy":  {"skip", "force"},
+}
+
+// State of a container an update has to handle explicitly, empty for a running or stopped container
+func updateState(c Container) string {
+	switch {
+	case c.State == "paused" || c.State == "restarting":
+		return c.State
+	// the list reports the health in the status, e.g. "Up 5 minutes (unhealthy)"
+	case strings.Contains(c.Status, "(unhealthy)"):
+		return "unhealthy"
+	}
+	return ""
+}
+
+// The state of c and what the update does with it: skip, unpause or force, from its label, update.states or the default
+func updateStateAction(c Container) (string, string, error) {
+	state := updateState(c)
+	if state == "" {
+		return "", "", nil
+	}
+	action := defaultStateActions[state]
+	if configured, ok := config.Update.States[state]; ok {
+		action = configured
+	}
+	if label, ok := c.Labels[labelUpdateState]; ok {
+		action = label
+	}
+	return state, action, validateStateAction(state, action)
+}
+
+// Check that action is allowed for a container in state
+func validateStateAction(state string, action string) error {
+	actions, ok := stateActions[state]
+	if !ok {
+		return fmt.Errorf("unknown state %q, expected one of %s", state, strings.Join(sortedKeys(stateActions), ", "))
+	}
+	if !slices.Contains(actions, action) {
+		return fmt.Errorf("unknown action %q for a %s container, expected one of %s", action, state, strings.Join(actions, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Check if an output path is uploaded rather than written, e.g. s3://bucket/hosts/nas.json
+func isRemoteOutput(path string) bool {
+	for _, scheme := range []string{"s3://", "gs://", "http://", "https://"} {
+		if strings.HasPrefix(path, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// Upload the rendered output to an S3 or GCS bucket, or PUT it to an HTTP endpoint
+func uploadOutput(path string, data []byte, contentType string) error {
+	u, err := url.Parse(path)
+	if err != nil {
+		return fmt.Errorf("error while parsing output URL: %s", err)
+	}
+
+	var req *http.Request
+	switch u.Scheme {
+	case "s3":
+		req, err = s3PutRequest(u.Host, strings.TrimPrefix(u.Path, "/"), data)
+	case "gs":
+		req, err = gcsUploadRequest(u.Host, strings.TrimPrefix(u.Path, "/"), data)
+	default:
+		// credentials in the URL are sent with basic auth
+		req, err = http.NewRequest("PUT", path, bytes.NewReader(data))
+	}
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	setRequestHeaders(req)
+
+	client := &http.Client{
+		Transport: transport,
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error while uploading to %s: %s", req.URL.Host, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("error %s from %s: %s", resp.Status, req.URL.Host, string(body))
+	}
+	return nil
+}
+
+// Escape a path for AWS Signature Version 4, which only leaves the unreserved characters and the slashes
+func awsEscapePath(path string) string {
+	var b strings.Builder
+	for _, c := range []byte(path) {
+		if c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || strings.IndexByte("-_.~/", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// PUT request of an S3 object, signed with the AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY of the environment,
+// sent to AWS_ENDPOINT_URL for S3 compatible stores such as MinIO
+// ref: https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html
+func s3PutRequest(bucket string, key string, data []byte) (*http.Request, error) {
+	accessKey, secretKey := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if accessKey == "" || secretKey == "" {
+		return nil, fmt.Errorf("no S3 credentials, set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	// path-style requests for custom endpoints, virtual-hosted style for AWS
+	endpoint := "https://" + bucket + ".s3." + region + ".amazonaws.com"
+	path := "/" + key
+	if custom := os.Getenv("AWS_ENDPOINT_URL"); custom != "" {
+		endpoint = strings.TrimSuffix(custom, "/")
+		path = "/" + bucket + "/" + key
+	}
+	escapedPath := awsEscapePath(path)
+
+	req, err := http.NewRequest("PUT", endpoint+escapedPath, bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("error while creating request: %s", err)
+	}
+	req.URL.RawPath = escapedPath
+
+	sum := sha256.Sum256(data)
+	payloadHash := hex.EncodeToString(sum[:])
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	canonicalHeaders := "host:" + req.URL.Host + "\nx-amz-content-sha256:" + payloadHash + "\nx-amz-date:" + amzDate + "\n"
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	if token := os.Getenv("AWS_SESSION_TOKEN"); token != "" {
+		req.Header.Set("X-Amz-Security-Token", token)
+		canonicalHeaders += "x-amz-security-token:" + token + "\n"
+		signedHeaders += ";x-amz-security-token"
+	}
+	canonicalRequest := strings.Join([]string{"PUT", escapedPath, "", canonicalHeaders, signedHeaders, payloadHash}, "\n")
+
+	scope := now.Format("20060102") + "/" + region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	signingKey := []byte("AWS4" + secretKey)
+	for _, part := range []string{now.Format("20060102"), region, "s3", "aws4_request"} {
+		signingKey = hmacSHA256(signingKey, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", accessKey, scope, signedHeaders, signature))
+	return req, nil
+}
+
+// Access token of Google Cloud: GOOGLE_OAUTH_ACCESS_TOKEN, or the service account of the instance from the metadata server
+func gcsAccessToken() (string, error) {
+	if token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"); token != "" {
+		return token, nil
+	}
+
+	req, err := http.NewRequest("GET", "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token", nil)
+	if err != nil {
+		return "", fmt.Errorf("error while creating request: %s", err)
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("no GCS credentials, set GOOGLE_OAUTH_ACCESS_TOKEN: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("error %s from the metadata server", resp.Status)
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&token)
+	if err != nil {
+		return "", fmt.Errorf("error while decoding access token: %s", err)
+	}
+	return token.AccessToken, nil
+}
+
+// Upload request of a GCS object
+// ref: https://cloud.google.com/storage/docs/uploading-objects#uploading-an-object
+func gcsUploadRequest(bucket string, object string, data []byte) (*http.Request, error) {
+	token, err := gcsAccessToken()
+	if err != nil {
+		return nil, err
+	}
+
+	query := url.Values{"uploadType": {"media"}, "name": {object}}
+	endpoint := "https://storage.googleapis.com/upload/storage/v1/b/" + url.PathEscape(bucket) + "/o?" + query.Encode()
+	req, err := http.NewRequest("POST", endpoint, bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("error while creating request: %s", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req, nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"net/http"
+	"runtime/debug"
+)
+
+// Version of the tool, set at build time with -ldflags "-X main.version=v1.2.3"
+var version string
+
+// User-Agent of the requests, tool/version by default
+var userAgent string
+
+// The -ldflags version, or the module version of go install, dev for other builds
+func toolVersion() string {
+	if version != "" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "dev"
+}
+
+func defaultUserAgent() string {
+	return commandName + "/" + toolVersion() + " (+https://github.com/baohuiming/docker-check-is-latest)"
+}
+
+// Set the User-Agent and the headers configured for the host of req, e.g. those a corporate proxy requires
+func setRequestHeaders(req *http.Request) {
+	if userAgent != "" {
+		req.Header.Set("User-Agent", userAgent)
+	} else {
+		req.Header.Set("User-Agent", defaultUserAgent())
+	}
+	rc, _ := requestRegistryConfig(req)
+	for name, value := range rc.Headers {
+		req.Header.Set(name, value)
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// Container labels of the version check, for software whose image tags don't tell the running version,
+// e.g. apps updating themselves in-place
+const (
+	labelVersionCmd  = "is-latest.version-cmd"  // shell command run in the container, printing its version or digest
+	labelVersionURL  = "is-latest.version-url"  // JSON endpoint of the latest version
+	labelVersionPath = "is-latest.version-path" // JSONPath of the version in the response, e.g. $.tag_name
+)
+
+// Time the version command has to print the version
+const versionCmdTimeout = 30 * time.Second
+
+// Versions compared by a version check
+type VersionCheck struct {
+	Current string `json:"current"`
+	Latest  string `json:"latest"`
+	Source  string `json:"source"` // command for the version command, release for the GitHub Releases
+}
+
+// Source of the latest version of an image without labels, from the config file
+type VersionSource struct {
+	Command string `json:"command"`
+	URL     string `json:"url"`
+	Path    string `json:"path"`
+}
+
+// Version check of c from its labels or the config entry of its image, nil without a command
+func versionSource(c Container, imageName string) *VersionSource {
+	source := config.VersionCheck[imageName]
+	if cmd, ok := c.Labels[labelVersionCmd]; ok {
+		source = VersionSource{Command: cmd, URL: c.Labels[labelVersionURL], Path: c.Labels[labelVersionPath]}
+	}
+	if source.Command == "" {
+		return nil
+	}
+	return &source
+}
+
+// Run a shell command in the container and return its trimmed stdout
+func execVersionCmd(ctx context.Context, c Container, cmd string) (string, error) {
+	// a command can change anything in the container
+	if err := checkWritable("exec in " + c.Names[0]); err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(ctx, versionCmdTimeout)
+	defer cancel()
+
+	cli, err := NewDockerClient(c.Endpoint)
+	if err != nil {
+		return "", err
+	}
+	defer cli.Close()
+
+	exec, err := cli.ContainerExecCreate(ctx, c.ID, container.ExecOptions{Cmd: []string{"sh", "-c", cmd}, AttachStdout: true, AttachStderr: true})
+	if err != nil {
+		return "", fmt.Errorf("error while creating exec: %s", err)
+	}
+	resp, err := cli.ContainerExecAttach(ctx, exec.ID, container.ExecAttachOptions{})
+	if err != nil {
+		return "", fmt.Errorf("error while attaching exec: %s", err)
+	}
+	defer resp.Close()
+
+	var stdout, stderr bytes.Buffer
+	_, err = stdcopy.StdCopy(&stdout, &stderr, resp.Reader)
+	if err != nil {
+		return "", fmt.Errorf("error while reading exec output: %s", err)
+	}
+	inspect, err := cli.ContainerExecInspect(ctx, exec.ID)
+	if err != nil {
+		return "", fmt.Errorf("error while inspecting exec: %s", err)
+	}
+	if inspect.ExitCode != 0 {
+		return "", fmt.Errorf("version command exited with %d: %s", inspect.ExitCode, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// Value at a JSONPath of the form $.a.b[0].c in data
+func jsonPath(data any, path string) (any, error) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	for _, segment := range strings.Split(path, ".") {
+		if segment == "" {
+			continue
+		}
+		key, indexes, _ := strings.Cut(segment, "[")
+		if key != "" {
+			object, ok := data.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("%s is not an object", key)
+			}
+			if data, ok = object[key]; !ok {
+				return nil, fmt.Errorf("no %s", key)
+			}
+		}
+		for _, index := range strings.Split(strings.TrimSuffix(indexes, "]"), "][") {
+			if index == "" {
+				continue
+			}
+			i, err := strconv.Atoi(index)
+			array, ok := data.([]any)
+			if err != nil || !ok || i < 0 || i >= len(array) {
+				return nil, fmt.Errorf("no index %s in %s", index, segment)
+			}
+			data = array[i]
+		}
+	}
+	return data, nil
+}
+
+// Fetch the latest version at path of the JSON response of url
+func latestVersion(ctx context.Context, url string, path string) (string, error) {
+	resp, err := httpFetch(ctx, url, http.Header{"Accept": {"application/json"}})
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("error while getting %s: status %d", url, resp.StatusCode)
+	}
+	var data any
+	err = json.Unmarshal(resp.Body, &data)
+	if err != nil {
+		return "", fmt.Errorf("error while unmarshalling %s: %s", url, err)
+	}
+	value, err := jsonPath(data, path)
+	if err != nil {
+		return "", fmt.Errorf("error while reading %s of %s: %s", path, url, err)
+	}
+	switch v := value.(type) {
+	case string:
+		return strings.TrimSpace(v), nil
+	case float64, bool:
+		return fmt.Sprint(v), nil
+	}
+	return "", fmt.Errorf("%s of %s is not a version", path, url)
+}
+
+// Replace the status of result by comparing the output of the version command with the latest version,
+// a leading v is ignored on either side. A failed comparison keeps the verdict of the registry with its error.
+func checkVersionCmd(ctx context.Context, c Container, source *VersionSource, result *CheckResult) {
+	if source.URL == "" {
+		result.Error, result.ErrorCode = "the version command has no "+labelVersionURL+" to compare against", ""
+		return
+	}
+	current, err := execVersionCmd(ctx, c, source.Command)
+	if err != nil {
+		result.Error, result.ErrorCode = err.Error(), errorCode(err)
+		return
+	}
+	latest, err := latestVersion(ctx, source.URL, source.Path)
+	if err != nil {
+		result.Error, result.ErrorCode = err.Error(), errorCode(err)
+		return
+	}
+
+	result.Error, result.ErrorCode, result.Severity = "", "", ""
+	result.VersionCheck = &VersionCheck{Current: current, Latest: latest, Source: "command"}
+	if strings.TrimPrefix(current, "v") == strings.TrimPrefix(latest, "v") {
+		result.IsLatest = "yes"
+		return
+	}
+	result.IsLatest = "no"
+	result.Severity = versionSeverity(current, latest)
+}
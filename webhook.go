@@ -0,0 +1,150 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/docker/docker/api/types/filters"
+)
+
+// Secret of the registry push webhooks, the endpoint is disabled without one
+var webhookSecret string
+
+// Push event payloads of the registries, only the fields naming the pushed repository
+// ref: https://docs.docker.com/docker-hub/webhooks/
+// ref: https://docs.github.com/en/webhooks/webhook-events-and-payloads#package
+// ref: https://goharbor.io/docs/main/working-with-projects/project-configuration/configure-webhooks/
+type webhookPayload struct {
+	// Docker Hub
+	Repository struct {
+		RepoName string `json:"repo_name"` // e.g. library/nginx
+	} `json:"repository"`
+
+	// GitHub
+	Package struct {
+		Name        string `json:"name"`
+		PackageType string `json:"package_type"`
+		Owner       struct {
+			Login string `json:"login"`
+		} `json:"owner"`
+	} `json:"package"`
+
+	// Harbor
+	EventData struct {
+		Resources []struct {
+			ResourceURL string `json:"resource_url"` // e.g. harbor.example.com/library/nginx:1.25
+		} `json:"resources"`
+	} `json:"event_data"`
+}
+
+// Image names of the repositories pushed according to the payload
+func (p webhookPayload) repositories() []string {
+	var repositories []string
+	if p.Repository.RepoName != "" {
+		repositories = append(repositories, "docker.io/"+p.Repository.RepoName)
+	}
+	if p.Package.Name != "" && strings.EqualFold(p.Package.PackageType, "container") {
+		repositories = append(repositories, "ghcr.io/"+strings.ToLower(p.Package.Owner.Login+"/"+p.Package.Name))
+	}
+	for _, resource := range p.EventData.Resources {
+		repositories = append(repositories, resource.ResourceURL)
+	}
+
+	for i, repository := range repositories {
+		repositories[i], _ = parseReference(repository)
+	}
+	return repositories
+}
+
+// Check if the request carries the secret: as the token query parameter, the Authorization header
+// (Harbor's auth header) or the HMAC signature of the body (GitHub)
+func webhookAuthorized(r *http.Request, body []byte) bool {
+	secret := []byte(webhookSecret)
+	if token := r.URL.Query().Get("token"); token != "" {
+		return subtle.ConstantTimeCompare([]byte(token), secret) == 1
+	}
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		return subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, "Bearer ")), secret) == 1
+	}
+	if signature, ok := strings.CutPrefix(r.Header.Get("X-Hub-Signature-256"), "sha256="); ok {
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(body)
+		expected := hex.EncodeToString(mac.Sum(nil))
+		return hmac.Equal([]byte(signature), []byte(expected))
+	}
+	return false
+}
+
+// POST /api/v1/webhook: re-check the containers running a pushed repository
+func handleWebhook(w http.ResponseWriter, r *http.Request) {
+	if webhookSecret == "" {
+		http.Error(w, "webhooks are not enabled, start with -webhook_secret", http.StatusNotFound)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !webhookAuthorized(r, body) {
+		http.Error(w, "invalid webhook secret", http.StatusUnauthorized)
+		return
+	}
+
+	var payload webhookPayload
+	err = json.Unmarshal(body, &payload)
+	if err != nil {
+		http.Error(w, "invalid payload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	repositories := payload.repositories()
+	if len(repositories) == 0 {
+		http.Error(w, "no pushed repository in payload", http.StatusBadRequest)
+		return
+	}
+
+	// registries time out slow webhook receivers, check in the background
+	w.WriteHeader(http.StatusAccepted)
+	go checkPushed(repositories)
+}
+
+// Check, and with -update update, the containers running one of the repositories
+func checkPushed(repositories []string) {
+	containers, err := GetDockerPortainerList(filters.NewArgs())
+	if err != nil {
+		log.Println("Unable to get docker list:", err)
+		return
+	}
+
+	filter := filters.NewArgs()
+	for _, c := range containers {
+		imageName, _ := parseReference(localReference(c))
+		for _, repository := range repositories {
+			if imageName == repository {
+				filter.Add("id", c.ID)
+			}
+		}
+	}
+	if filter.Len() == 0 {
+		log.Println("No container runs pushed repository:", strings.Join(repositories, ", "))
+		return
+	}
+
+	// a pushed tag may be one that was missing
+	for _, repository := range repositories {
+		forgetFailures(repository)
+		forgetLookups(repository)
+	}
+	log.Println("Checking containers of pushed repository:", strings.Join(repositories, ", "))
+	err = run(filter, 0)
+	if err != nil {
+		log.Println("Unable to check containers:", err)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// A weekly time range such as "Sat,Sun 02:00-05:00", "Mon-Fri 23:00-01:00" or "03:00-04:00" (every day)
+type MaintenanceWindow struct {
+	Days  []time.Weekday // empty means every day
+	Start int            // minutes since midnight
+	End   int            // minutes since midnight, before Start when the window spans midnight
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %s, expecting HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func parseWeekdays(s string) ([]time.Weekday, error) {
+	var days []time.Weekday
+	for _, part := range strings.Split(s, ",") {
+		from, to, isRange := strings.Cut(strings.ToLower(part), "-")
+		first, ok := weekdays[from]
+		if !ok {
+			return nil, fmt.Errorf("invalid weekday %s", from)
+		}
+		last := first
+		if isRange {
+			if last, ok = weekdays[to]; !ok {
+				return nil, fmt.Errorf("invalid weekday %s", to)
+			}
+		}
+		for d := first; ; d = (d + 1) % 7 {
+			days = append(days, d)
+			if d == last {
+				break
+			}
+		}
+	}
+	return days, nil
+}
+
+// Parse windows separated by ";", e.g. "Sat,Sun 02:00-05:00; Wed 03:00-04:00"
+func parseMaintenanceWindows(s string) ([]MaintenanceWindow, error) {
+	var windows []MaintenanceWindow
+	for _, part := range strings.Split(s, ";") {
+		fields := strings.Fields(part)
+		if len(fields) == 0 {
+			continue
+		}
+
+		var w MaintenanceWindow
+		var err error
+		if len(fields) == 2 {
+			w.Days, err = parseWeekdays(fields[0])
+			if err != nil {
+				return nil, err
+			}
+			fields = fields[1:]
+		} else if len(fields) != 1 {
+			return nil, fmt.Errorf("invalid maintenance window %s", part)
+		}
+
+		start, end, ok := strings.Cut(fields[0], "-")
+		if !ok {
+			return nil, fmt.Errorf("invalid maintenance window %s, expecting HH:MM-HH:MM", part)
+		}
+		if w.Start, err = parseClock(start); err != nil {
+			return nil, err
+		}
+		if w.End, err = parseClock(end); err != nil {
+			return nil, err
+		}
+		if w.Start == w.End {
+			return nil, fmt.Errorf("invalid maintenance window %s, it starts when it ends", part)
+		}
+		windows = append(windows, w)
+	}
+	return windows, nil
+}
+
+func (w MaintenanceWindow) onDay(d time.Weekday) bool {
+	return len(w.Days) == 0 || slices.Contains(w.Days, d)
+}
+
+// Check if t falls inside the window
+func (w MaintenanceWindow) Contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	if w.Start < w.End {
+		return w.onDay(t.Weekday()) && minute >= w.Start && minute < w.End
+	}
+	// spanning midnight, the part after midnight belongs to the previous day's window
+	return (w.onDay(t.Weekday()) && minute >= w.Start) ||
+		(w.onDay((t.Weekday()+6)%7) && minute < w.End)
+}
+
+func inMaintenanceWindows(windows []MaintenanceWindow, t time.Time) bool {
+	for _, w := range windows {
+		if w.Contains(t) {
+			return true
+		}
+	}
+	return false
+}